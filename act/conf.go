@@ -0,0 +1,265 @@
+package act
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Section is a begin/end block of an ACT configuration file. Values are
+// int64, float64, string, []int64, []float64, []string or nested Sections.
+type Section map[string]interface{}
+
+// Load reads an ACT configuration file such as layout.conf. Included files
+// are merged into the section that includes them.
+func Load(path string) (Section, error) {
+	result := Section{}
+	err := load(path, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func Read(r io.Reader) (Section, error) {
+	result := Section{}
+	err := read("", r, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func load(path string, s Section) error {
+	fptr, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fptr.Close()
+	return read(path, fptr, s)
+}
+
+func stripComment(line string) string {
+	inString := false
+	escape := false
+	for i, c := range line {
+		if escape {
+			escape = false
+		} else if inString && c == '\\' {
+			escape = true
+		} else if c == '"' {
+			inString = !inString
+		} else if !inString && c == '#' {
+			return line[0:i]
+		}
+	}
+	return line
+}
+
+// split separates a line into its arguments, quoted strings may contain
+// spaces
+func split(line string) ([]string, error) {
+	var args []string
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' || line[i] == '\r' {
+			i++
+		} else if line[i] == '"' {
+			j := i + 1
+			for j < len(line) && line[j] != '"' {
+				if line[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(line) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			args = append(args, line[i:j+1])
+			i = j + 1
+		} else {
+			j := i
+			for j < len(line) && line[j] != ' ' && line[j] != '\t' && line[j] != '\r' {
+				j++
+			}
+			args = append(args, line[i:j])
+			i = j
+		}
+	}
+	return args, nil
+}
+
+func unquote(arg string) (string, error) {
+	if len(arg) < 2 || arg[0] != '"' || arg[len(arg)-1] != '"' {
+		return "", fmt.Errorf("expected a string, found '%s'", arg)
+	}
+	return strconv.Unquote(arg)
+}
+
+func read(path string, r io.Reader, top Section) error {
+	stack := []Section{top}
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		args, err := split(stripComment(scanner.Text()))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, number, err)
+		} else if len(args) == 0 {
+			continue
+		}
+
+		curr := stack[len(stack)-1]
+		if args[0] == "end" {
+			if len(stack) <= 1 {
+				return fmt.Errorf("%s:%d: unmatched end", path, number)
+			}
+			stack = stack[0 : len(stack)-1]
+			continue
+		} else if len(args) < 2 {
+			return fmt.Errorf("%s:%d: expected '%s <name> ...'", path, number, args[0])
+		}
+
+		name := args[1]
+		switch args[0] {
+		case "include":
+			inc, err := unquote(args[1])
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, number, err)
+			}
+			if _, err := os.Stat(inc); err != nil && !filepath.IsAbs(inc) {
+				inc = filepath.Join(filepath.Dir(path), inc)
+			}
+			err = load(inc, curr)
+			if err != nil {
+				return err
+			}
+			continue
+		case "begin":
+			sub, ok := curr[name].(Section)
+			if !ok {
+				sub = Section{}
+				curr[name] = sub
+			}
+			stack = append(stack, sub)
+			continue
+		}
+
+		values := args[2:]
+		if !strings.HasSuffix(args[0], "_table") && len(values) != 1 {
+			return fmt.Errorf("%s:%d: expected '%s %s <value>'", path, number, args[0], name)
+		}
+
+		switch args[0] {
+		case "int":
+			curr[name], err = strconv.ParseInt(values[0], 10, 64)
+		case "real":
+			curr[name], err = strconv.ParseFloat(values[0], 64)
+		case "string":
+			curr[name], err = unquote(values[0])
+		case "int_table":
+			table := make([]int64, len(values))
+			for i, v := range values {
+				if table[i], err = strconv.ParseInt(v, 10, 64); err != nil {
+					break
+				}
+			}
+			curr[name] = table
+		case "real_table":
+			table := make([]float64, len(values))
+			for i, v := range values {
+				if table[i], err = strconv.ParseFloat(v, 64); err != nil {
+					break
+				}
+			}
+			curr[name] = table
+		case "string_table":
+			table := make([]string, len(values))
+			for i, v := range values {
+				if table[i], err = unquote(v); err != nil {
+					break
+				}
+			}
+			curr[name] = table
+		default:
+			err = fmt.Errorf("unrecognized type '%s'", args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, number, err)
+		}
+	}
+	if len(stack) > 1 {
+		return fmt.Errorf("%s: missing end", path)
+	}
+	return scanner.Err()
+}
+
+// Lookup finds a value by its dotted path, for example "general.scale"
+func (s Section) Lookup(path string) (interface{}, bool) {
+	var curr interface{} = s
+	for _, name := range strings.Split(path, ".") {
+		sec, ok := curr.(Section)
+		if !ok {
+			return nil, false
+		}
+		curr, ok = sec[name]
+		if !ok {
+			return nil, false
+		}
+	}
+	return curr, true
+}
+
+func (s Section) Section(path string) Section {
+	v, _ := s.Lookup(path)
+	sec, _ := v.(Section)
+	return sec
+}
+
+func (s Section) Int(path string) (int64, bool) {
+	v, _ := s.Lookup(path)
+	i, ok := v.(int64)
+	return i, ok
+}
+
+// Real returns a numeric value, converting ints
+func (s Section) Real(path string) (float64, bool) {
+	v, _ := s.Lookup(path)
+	if i, ok := v.(int64); ok {
+		return float64(i), true
+	}
+	f, ok := v.(float64)
+	return f, ok
+}
+
+func (s Section) String(path string) (string, bool) {
+	v, _ := s.Lookup(path)
+	str, ok := v.(string)
+	return str, ok
+}
+
+func (s Section) IntTable(path string) []int64 {
+	v, _ := s.Lookup(path)
+	table, _ := v.([]int64)
+	return table
+}
+
+func (s Section) RealTable(path string) []float64 {
+	v, _ := s.Lookup(path)
+	if table, ok := v.([]int64); ok {
+		result := make([]float64, len(table))
+		for i, t := range table {
+			result[i] = float64(t)
+		}
+		return result
+	}
+	table, _ := v.([]float64)
+	return table
+}
+
+func (s Section) StringTable(path string) []string {
+	v, _ := s.Lookup(path)
+	table, _ := v.([]string)
+	return table
+}
@@ -0,0 +1,64 @@
+package act
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRead(t *testing.T) {
+	conf, err := Read(strings.NewReader(`# comment
+begin general
+  real scale 5   # lambda in nm
+  int metals 2
+  string name "a # b"
+end
+begin materials
+  begin metal
+    string_table m1_gds "met1.drawing" "met1.pin"
+    int_table m1_gds_bloat 0 1
+  end
+end
+real_table widths 1 2.5
+`))
+	assert.NoError(t, err)
+
+	scale, ok := conf.Real("general.scale")
+	assert.True(t, ok)
+	assert.Equal(t, 5.0, scale)
+	metals, ok := conf.Int("general.metals")
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), metals)
+	name, ok := conf.String("general.name")
+	assert.True(t, ok)
+	assert.Equal(t, "a # b", name)
+	assert.Equal(t, []string{"met1.drawing", "met1.pin"}, conf.StringTable("materials.metal.m1_gds"))
+	assert.Equal(t, []int64{0, 1}, conf.IntTable("materials.metal.m1_gds_bloat"))
+	assert.Equal(t, []float64{1, 2.5}, conf.RealTable("widths"))
+	assert.NotNil(t, conf.Section("materials"))
+
+	_, ok = conf.Int("general.missing")
+	assert.False(t, ok)
+
+	_, err = Read(strings.NewReader("begin general\n"))
+	assert.Error(t, err)
+	_, err = Read(strings.NewReader("int x y\n"))
+	assert.Error(t, err)
+}
+
+func TestInclude(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "general.conf"), []byte("int metals 3\n"), 0644)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "layout.conf"), []byte("begin general\ninclude \"general.conf\"\nend\n"), 0644)
+	assert.NoError(t, err)
+
+	conf, err := Load(filepath.Join(dir, "layout.conf"))
+	assert.NoError(t, err)
+	metals, ok := conf.Int("general.metals")
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), metals)
+}
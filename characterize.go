@@ -0,0 +1,225 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/timing"
+	"git.broccolimicro.io/Broccoli/pr.git/spice"
+)
+
+var characterizeCommand = &command{
+	name:  "characterize",
+	usage: "[flags] <measurements...>",
+	desc:  "generate timing profile entries from spice or gate-level measurements",
+	setup: func(fs *flag.FlagSet, c *config) func(args []string) error {
+		out := fs.String("o", "", "generated profile to create or update, hand-written profiles include it")
+		pkg := fs.String("pkg", "git.broccolimicro.io/Broccoli/pr.git/chp", "package of the cells, entries are keyed by <pkg>.<cell> like Init looks them up")
+		prof := fs.String("profile", c.Profile, "hand-written profile the simulations load, checked for the characterized cells")
+		return func(args []string) error {
+			if len(args) == 0 {
+				return usagef("characterize: expected a measurement file")
+			} else if *out == "" {
+				return usagef("characterize: no output profile specified, use -o")
+			}
+			cells, err := characterize(*out, *pkg, args)
+			if err != nil {
+				return err
+			}
+			if *prof != "" {
+				return checkProfile(*prof, *out, cells)
+			}
+			return nil
+		}
+	},
+}
+
+// cellKey returns the profile key of a cell. Generic components are named
+// with [...] as Go does, like Buffer[...].
+func cellKey(pkg, cell string) string {
+	if pkg == "" || strings.Contains(cell, "/") {
+		return cell
+	}
+	return pkg + "." + cell
+}
+
+// round drops the digits below a femtosecond or an attojoule, which are
+// only noise from the unit conversions
+func round(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+// summarize returns the mean of values, or a normal distribution if they
+// vary between runs of the same cell
+func summarize(values []float64) timing.Distribution {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	if len(values) > 1 {
+		variance /= float64(len(values) - 1)
+	}
+	sigma := round(math.Sqrt(variance))
+	if sigma == 0 {
+		return timing.Constant(round(mean))
+	}
+	return timing.Normal{Mu: round(mean), Sigma: sigma}
+}
+
+// profiles is an ordered set of profile entries
+type profiles struct {
+	keys   []string
+	values map[string]map[string]timing.Distribution
+	order  map[string][]string
+}
+
+func (p *profiles) add(key string) {
+	if _, ok := p.values[key]; !ok {
+		p.keys = append(p.keys, key)
+		p.values[key] = map[string]timing.Distribution{}
+	}
+}
+
+func (p *profiles) set(key, name string, d timing.Distribution) {
+	p.add(key)
+	if _, ok := p.values[key][name]; !ok {
+		p.order[key] = append(p.order[key], name)
+	}
+	p.values[key][name] = d
+}
+
+// generated marks the profiles written by characterize, the only ones it
+// will overwrite
+const generated = "# written by pr characterize"
+
+// handWritten matches what a rewrite would lose: comments other than the
+// header, includes, constants and extends
+var handWritten = regexp.MustCompile(`(?m)(#|//|\binclude\s*"|\bextends\s*"|^\s*[A-Za-z_][A-Za-z0-9_]*\s*=)`)
+
+func (p *profiles) write(w io.Writer) error {
+	fmt.Fprintf(w, "%s\n{\n", generated)
+	for _, key := range p.keys {
+		fmt.Fprintf(w, "\t\"%s\": {\n", key)
+		for _, name := range p.order[key] {
+			fmt.Fprintf(w, "\t\t\"%s\": %s,\n", name, p.values[key][name])
+		}
+		fmt.Fprintf(w, "\t},\n")
+	}
+	_, err := fmt.Fprintf(w, "}\n")
+	return err
+}
+
+// characterize reads the measurements and writes their profile entries into
+// out. The forward latency becomes d0R and the rest of the cycle d0, the
+// energy e0, and it returns the keys of the measured cells. Entries and keys
+// already in out that weren't measured are kept.
+// Only a profile written by characterize is updated, since comments,
+// includes, constants and extends don't survive being rewritten. A
+// hand-written profile includes the generated one instead.
+func characterize(out, pkg string, paths []string) ([]string, error) {
+	p := &profiles{
+		values: map[string]map[string]timing.Distribution{},
+		order:  map[string][]string{},
+	}
+	if b, err := os.ReadFile(out); err == nil {
+		if !strings.HasPrefix(string(b), generated+"\n") || handWritten.Match(b[len(generated):]) {
+			return nil, fmt.Errorf("'%s' isn't a profile generated by pr characterize, write the measurements to their own profile and include it from this one", out)
+		}
+		set, err := timing.ParseProfileSet(out, b)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range set.Keys() {
+			p.add(key)
+			prof := set.Find(key)
+			for _, name := range prof.Keys() {
+				p.set(key, name, prof.Dist(name))
+			}
+		}
+	}
+
+	var cells []string
+	latency := map[string][]float64{}
+	cycle := map[string][]float64{}
+	energy := map[string][]float64{}
+	for _, path := range paths {
+		fptr, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		measurements, err := spice.ReadMeasurements(fptr, name)
+		fptr.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		for _, m := range measurements {
+			key := cellKey(pkg, m.Cell)
+			if _, ok := latency[key]; !ok {
+				cells = append(cells, key)
+				latency[key] = nil
+			}
+			if !math.IsNaN(m.Latency) {
+				latency[key] = append(latency[key], m.Latency)
+				if !math.IsNaN(m.Cycle) {
+					cycle[key] = append(cycle[key], math.Max(0, m.Cycle-m.Latency))
+				}
+			}
+			if !math.IsNaN(m.Energy) {
+				energy[key] = append(energy[key], m.Energy)
+			}
+		}
+	}
+
+	for _, key := range cells {
+		if _, ok := p.values[key]; !ok {
+			// the input is acknowledged as part of the measured cycle
+			p.set(key, "d0L", timing.Constant(0))
+		}
+		if len(latency[key]) > 0 {
+			p.set(key, "d0R", summarize(latency[key]))
+		}
+		if len(cycle[key]) > 0 {
+			p.set(key, "d0", summarize(cycle[key]))
+		}
+		if len(energy[key]) > 0 {
+			p.set(key, "e0", summarize(energy[key]))
+		}
+		fmt.Printf("characterized %s\n", key)
+	}
+
+	fptr, err := os.Create(out)
+	if err != nil {
+		return nil, err
+	}
+	defer fptr.Close()
+	return cells, p.write(fptr)
+}
+
+// checkProfile loads the hand-written profile and warns about the cells
+// it doesn't define, which happens when it doesn't include out
+func checkProfile(path, out string, cells []string) error {
+	set, err := timing.LoadProfileSet(path)
+	if err != nil {
+		return err
+	}
+	for _, key := range cells {
+		if set.Find(key) == nil {
+			fmt.Fprintf(os.Stderr, "warning: '%s' doesn't define %s, include \"%s\" from it\n", path, key, out)
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package stream
 
 import (
 	"errors"
+	"fmt"
 
 	"git.broccolimicro.io/Broccoli/pr.git/chp"
 	"git.broccolimicro.io/Broccoli/pr.git/chp/timing"
@@ -12,11 +13,11 @@ func Buffer[T interface{}](g chp.Globals, L Receiver[T], R Sender[T]) {
 	p := g.Init(L, R)
 	defer g.Done()
 
-	d0R := p.Find("d0R")
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	for {
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
 		x, tl := L.Recv()
 		tr := R.Send(x, tl+d0R)
 		g.Cycle(e0, tl, tr+d0)
@@ -27,11 +28,11 @@ func Copy[T interface{}](g chp.Globals, L Receiver[T], R []Sender[T]) {
 	p := g.Init(L, R)
 	defer g.Done()
 
-	d0R := p.Find("d0R")
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")*float64(len(R))
-
 	for {
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")*float64(len(R))
+
 		x, tl := L.Recv()
 		tr := timing.Max()
 		for _, r := range R {
@@ -45,10 +46,6 @@ func Split[T interface{}](g chp.Globals, C chp.Receiver[int], L Receiver[T], R [
 	p := g.Init(C, L, R)
 	defer g.Done()
 
-	d0R := p.Find("d0R")
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	for {
 		c, tc := C.Recv()
 		if c < 0 || c >= len(R) {
@@ -60,6 +57,10 @@ func Split[T interface{}](g chp.Globals, C chp.Receiver[int], L Receiver[T], R [
 
 		tr = tc
 		for !x.C {
+			d0R := p.Sample("d0R")
+			d0 := p.Sample("d0")
+			e0 := p.Sample("e0")
+
 			tc = tr
 			x, tl = L.Recv(tr)
 			tr = R[c].Send(x, tl+d0R)
@@ -74,10 +75,6 @@ func Merge[T interface{}](g chp.Globals, C chp.Receiver[int], L []Receiver[T], R
 	p := g.Init(C, L, R)
 	defer g.Done()
 
-	d0R := p.Find("d0R")
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	for {
 		c, tc := C.Recv()
 		if c < 0 || c >= len(L) {
@@ -89,6 +86,10 @@ func Merge[T interface{}](g chp.Globals, C chp.Receiver[int], L []Receiver[T], R
 
 		tr = tc
 		for !x.C {
+			d0R := p.Sample("d0R")
+			d0 := p.Sample("d0")
+			e0 := p.Sample("e0")
+
 			tc = tr
 			x, tl = L[c].Recv(tr)
 			tr = R.Send(x, tl+d0R)
@@ -103,12 +104,12 @@ func SerialToParallel[T interface{}](g chp.Globals, A Receiver[T], S []Sender[T]
 	p := g.Init(A, S)
 	defer g.Done()
 
-	d0R := p.Find("d0R")
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	i := 0
 	for {
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
 		a, ta := A.Recv()
 		ts := ta
 		if i < len(S) {
@@ -129,12 +130,12 @@ func ParallelToSerial[T interface{}](g chp.Globals, A []Receiver[T], S Sender[T]
 	p := g.Init(A, S)
 	defer g.Done()
 
-	d0R := p.Find("d0R")
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	i := 0
 	for {
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
 		a, ta := A[i].Recv()
 		ts := S.SendToken(a.C || i == len(A)-1, a.D, ta+d0R)
 
@@ -148,4 +149,163 @@ func ParallelToSerial[T interface{}](g chp.Globals, A []Receiver[T], S Sender[T]
 	}
 }
 
+func Demux[T interface{}](route func(T) int, g chp.Globals, L Receiver[T], R []Sender[T]) {
+	p := g.Init(L, R)
+	defer g.Done()
+
+	for {
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		// the first token of each stream chooses the destination
+		x, tl := L.Recv()
+		c := route(x.D)
+		if c < 0 || c >= len(R) {
+			panic(errors.New("demux destination out of bounds"))
+		}
+		tr := R[c].Send(x, tl+d0R)
+		g.Cycle(e0, tl, tr+d0)
+
+		for !x.C {
+			d0R = p.Sample("d0R")
+			d0 = p.Sample("d0")
+			e0 = p.Sample("e0")
+
+			x, tl = L.Recv()
+			tr = R[c].Send(x, tl+d0R)
+			g.Cycle(e0, tl, tr+d0)
+		}
+	}
+}
+
+// Crossbar routes each stream from L[i] whole to the output read from D[i].
+// Its inputs and outputs run as the processes in.<i> and out.<j>, which are
+// timed by the keys in.<i>.<name> and out.<j>.<name> of the Crossbar's
+// profile, falling back to <name> when they are missing. Their reads are
+// reported under the Crossbar in profile-usage, not under their own names.
+func Crossbar[T interface{}](g chp.Globals, D []chp.Receiver[int], L []Receiver[T], R []Sender[T]) {
+	p := g.Init()
+	defer g.Done()
+
+	if len(D) != len(L) {
+		panic(chp.Misconfigured)
+	}
+
+	X := make([][]Receiver[T], len(R))
+	for i := range L {
+		Xs, Xr := ChanArr[T](fmt.Sprintf("X.%d", i), len(R), 0)
+		for j := range R {
+			X[j] = append(X[j], Xr[j])
+		}
+		go crossbarIn(timing.WithPrefix(p, fmt.Sprintf("in.%d", i)), g.Sub("in.%d", i), D[i], L[i], Xs)
+	}
+	for j := range R {
+		go crossbarOut(timing.WithPrefix(p, fmt.Sprintf("out.%d", j)), g.Sub("out.%d", j), X[j], R[j])
+	}
+}
+
+// crossbarIn and crossbarOut draw their own samples from the profile of
+// the Crossbar, scoped to their port
+func crossbarIn[T interface{}](p timing.Profile, g chp.Globals, D chp.Receiver[int], L Receiver[T], X []Sender[T]) {
+	g.Init(D, L, X)
+	defer g.Done()
+
+	p = timing.WithRand(p, g.Rand())
+	for {
+		c, tc := D.Recv()
+		if c < 0 || c >= len(X) {
+			panic(errors.New("crossbar destination out of bounds"))
+		}
+
+		var tl, tr float64
+		var x bd.Token[bool, T]
+
+		tr = tc
+		for !x.C {
+			d0 := p.Sample("d0")
+			e0 := p.Sample("e0")
+
+			tc = tr
+			x, tl = L.Recv(tr)
+			tr = X[c].Send(x, tl)
+
+			g.Cycle(e0, tc, tr+d0)
+			tr = 0
+		}
+	}
+}
+
+func crossbarOut[T interface{}](p timing.Profile, g chp.Globals, X []Receiver[T], R Sender[T]) {
+	g.Init(X, R)
+	defer g.Done()
+
+	p = timing.WithRand(p, g.Rand())
+	for {
+		// streams are granted whole in the order they arrive
+		i, ta := chp.Select(g, X)
+
+		var tl, tr float64
+		var x bd.Token[bool, T]
+
+		tr = ta
+		for !x.C {
+			d0R := p.Sample("d0R")
+			d0 := p.Sample("d0")
+			e0 := p.Sample("e0")
+
+			ta = tr
+			x, tl = X[i].Recv(tr)
+			tr = R.Send(x, tl+d0R)
 
+			g.Cycle(e0, ta, tr+d0)
+			tr = 0
+		}
+	}
+}
+
+func Zip[T interface{}](g chp.Globals, L []Receiver[T], R Sender[[]T]) {
+	p := g.Init(L, R)
+	defer g.Done()
+
+	for {
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		// the streams must have the same length
+		values := make([]T, len(L))
+		last := true
+		t := timing.Max()
+		for j := 0; j < len(L); j++ {
+			x, tj := L[j].Recv()
+			values[j] = x.D
+			last = last && x.C
+			t.Add(tj)
+		}
+		tl := t.Get()
+		tr := R.SendToken(last, values, tl+d0R)
+		g.Cycle(e0, tl, tr+d0)
+	}
+}
+
+func Unzip[T interface{}](g chp.Globals, L Receiver[[]T], R []Sender[T]) {
+	p := g.Init(L, R)
+	defer g.Done()
+
+	for {
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		x, tl := L.Recv()
+		if len(x.D) != len(R) {
+			panic(errors.New("unzip token has the wrong length"))
+		}
+		tr := timing.Max(tl)
+		for j, r := range R {
+			tr.Add(r.SendToken(x.C, x.D[j], tl+d0R))
+		}
+		g.Cycle(e0, tl, tr.Get()+d0)
+	}
+}
@@ -3,6 +3,7 @@ package lsbf
 import (
 	"testing"
 	"math"
+	"time"
 
 	"git.broccolimicro.io/Broccoli/pr.git/chp"
 	"git.broccolimicro.io/Broccoli/pr.git/chp/bd/stream"
@@ -25,7 +26,8 @@ func TestIntegrationBuffer(t *testing.T) {
 	Ls, Lr := Chan("L", base, 0)
 	Rs, Rr := Chan("L", base, 0)
 
-	go chp.SourceN[int64](100, chp.RandomInt64(min, max), g.Sub("src"), Ls)
+	src := g.Sub("src")
+	go chp.SourceN[int64](100, chp.RandomInt64(src.Rand(), min, max), src, Ls)
 	go chp.Sink[int64](g.Sub("sink"), Rr)
 	go stream.Buffer(g.Sub("dut"), Lr.Raw(), Rs.Raw())
 }
@@ -48,7 +50,8 @@ func TestIntegrationCopy(t *testing.T) {
 	for i := 0; i < copies; i++ {
 		go chp.Sink[int64](g.Sub("sink.%d", i), Rr[i])
 	}
-	go chp.SourceN[int64](100, chp.RandomInt64(min, max), g.Sub("src"), Ls)
+	src := g.Sub("src")
+	go chp.SourceN[int64](100, chp.RandomInt64(src.Rand(), min, max), src, Ls)
 	go stream.Copy(g.Sub("dut"), Lr.Raw(), RawSenders(Rs))
 }
 
@@ -68,8 +71,10 @@ func TestIntegrationSplit(t *testing.T) {
 	Ls, Lr := Chan("L", base, 0)
 	Rs, Rr := ChanArr("R", choices, base, 0)
 
-	go chp.SourceN(100, chp.RandomInt(0, choices), g.Sub("src_C"), Cs)
-	go chp.Source[int64](chp.RandomInt64(min, max), g.Sub("src_L"), Ls)
+	srcC := g.Sub("src_C")
+	go chp.SourceN(100, chp.RandomInt(srcC.Rand(), 0, choices), srcC, Cs)
+	srcL := g.Sub("src_L")
+	go chp.Source[int64](chp.RandomInt64(srcL.Rand(), min, max), srcL, Ls)
 	for i := 0; i < choices; i++ {
 		go chp.Sink[int64](g.Sub("sink.%d", i), Rr[i])
 	}
@@ -92,11 +97,135 @@ func TestIntegrationMerge(t *testing.T) {
 	Ls, Lr := ChanArr("L", choices, base, 0)
 	Rs, Rr := Chan("R", base, 0)
 
-	go chp.SourceN(100, chp.RandomInt(0, choices), g.Sub("src_C"), Cs)
+	srcC := g.Sub("src_C")
+	go chp.SourceN(100, chp.RandomInt(srcC.Rand(), 0, choices), srcC, Cs)
 	for i := 0; i < choices; i++ {
-		go chp.Source[int64](chp.RandomInt64(min, max), g.Sub("src_L.%d", i), Ls[i])
+		srcL := g.Sub("src_L.%d", i)
+		go chp.Source[int64](chp.RandomInt64(srcL.Rand(), min, max), srcL, Ls[i])
 	}
 	go chp.Sink[int64](g.Sub("sink"), Rr)
 	go stream.Merge(g.Sub("dut"), Cr, RawReceivers(Lr), Rs.Raw())
 }
 
+
+func TestIntegrationDemux(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/stream/demux")
+	choices := param.Int(3, 2)
+	base := param.Int64(4, int64(16))
+
+	g, err := chp.New(out, profile)
+	assert.NoError(t, err)
+
+	Ls, Lr := Chan("L", base, 0)
+	Rs, Rr := ChanArr("R", choices, base, 0)
+
+	src := g.Sub("src")
+	go chp.SourceN[int64](100, chp.RandomInt64(src.Rand(), 0, math.MaxInt64), src, Ls)
+	values := make([][]int64, choices)
+	for i := 0; i < choices; i++ {
+		go func(g chp.Globals, i int) {
+			g.Init(Rr[i])
+			defer g.Done()
+			for {
+				x, t := Rr[i].Recv()
+				values[i] = append(values[i], x)
+				g.Cycle(0, t, t)
+			}
+		}(g.Sub("sink.%d", i), i)
+	}
+	// the least significant digit chooses the destination
+	route := func(d int64) int { return int(d) % choices }
+	go stream.Demux(route, g.Sub("dut"), Lr.Raw(), RawSenders(Rs))
+	g.Done()
+
+	total := 0
+	for i := range values {
+		for _, x := range values[i] {
+			assert.Equal(t, i, int(x%base)%choices)
+		}
+		total += len(values[i])
+	}
+	assert.Equal(t, 100, total)
+}
+
+func TestIntegrationCrossbar(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/stream/crossbar")
+	inputs := param.Int(3, 3)
+	outputs := param.Int(4, 2)
+	base := param.Int64(5, int64(16))
+	count := 20
+
+	g, err := chp.New(out, profile)
+	assert.NoError(t, err)
+	g.RandomTiming(100*time.Microsecond)
+
+	Ds, Dr := chp.ChanArr[int]("D", inputs, 0)
+	Ls, Lr := ChanArr("L", inputs, base, 0)
+	Rs, Rr := ChanArr("R", outputs, base, 0)
+
+	// input i sends i*1000+k to output k%outputs
+	for i := 0; i < inputs; i++ {
+		i := i
+		go chp.SourceN(int64(count), func(k int64) int { return int(k)%outputs }, g.Sub("dst.%d", i), Ds[i])
+		go chp.SourceN[int64](int64(count), func(k int64) int64 { return int64(i*1000)+k }, g.Sub("src.%d", i), Ls[i])
+	}
+
+	values := make([][]int64, outputs)
+	for j := 0; j < outputs; j++ {
+		go func(g chp.Globals, j int) {
+			g.Init(Rr[j])
+			defer g.Done()
+			for {
+				x, t := Rr[j].Recv()
+				values[j] = append(values[j], x)
+				g.Cycle(0, t, t)
+			}
+		}(g.Sub("sink.%d", j), j)
+	}
+
+	go stream.Crossbar(g.Sub("xbar"), Dr, RawReceivers(Lr), RawSenders(Rs))
+	g.Done()
+
+	total := 0
+	for j := range values {
+		// streams from each input stay whole and in order
+		last := map[int64]int64{}
+		for _, x := range values[j] {
+			i, k := x/1000, x%1000
+			assert.Equal(t, int64(j), k%int64(outputs))
+			if prev, ok := last[i]; ok {
+				assert.Greater(t, k, prev)
+			}
+			last[i] = k
+		}
+		total += len(values[j])
+	}
+	assert.Equal(t, inputs*count, total)
+}
+
+func TestIntegrationZip(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/stream/zip")
+	base := param.Int64(3, int64(16))
+
+	g, err := chp.New(out, profile)
+	assert.NoError(t, err)
+	defer g.Done()
+
+	Ls, Lr := ChanArr("L", 3, base, 0)
+	Zs, Zr := stream.Chan[[]int64]("Z", 0)
+	Rs, Rr := ChanArr("R", 3, base, 0)
+	Vs, Vr := chp.ChanArr[int64]("V", 3, 2)
+
+	// zipped streams must have the same length, every value here has three
+	// digits in base 16
+	for i := 0; i < 3; i++ {
+		src := g.Sub("src.%d", i)
+		go chp.SourceN[int64](100, chp.RandomInt64(src.Rand(), base*base, base*base*base), src, Ls[i], Vs[i])
+		go chp.SinkAndCheck[int64](chp.AreEqual[int64], g.Sub("sink.%d", i), Rr[i], Vr[i])
+	}
+	go stream.Zip(g.Sub("zip"), RawReceivers(Lr), Zs)
+	go stream.Unzip(g.Sub("unzip"), Zr, RawSenders(Rs))
+}
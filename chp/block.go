@@ -0,0 +1,102 @@
+package chp
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Direction of a port, seen from inside the block
+type Direction int
+
+const (
+	Input Direction = iota
+	Output
+)
+
+func (d Direction) String() string {
+	if d == Input {
+		return "input"
+	}
+	return "output"
+}
+
+// Port declares one port of a Block
+type Port struct {
+	Name string
+	Dir  Direction
+	// the type of the tokens
+	Type reflect.Type
+}
+
+// In declares an input port, bound to a Receiver[T]
+func In[T interface{}](name string) Port {
+	return Port{Name: name, Dir: Input, Type: reflect.TypeOf((*T)(nil)).Elem()}
+}
+
+// Out declares an output port, bound to a Sender[T]
+func Out[T interface{}](name string) Port {
+	return Port{Name: name, Dir: Output, Type: reflect.TypeOf((*T)(nil)).Elem()}
+}
+
+// Block is a process built out of other processes. It is declared once with
+// its ports and instantiated any number of times with Run, possibly inside
+// other blocks.
+type Block struct {
+	Name  string
+	Ports []Port
+	// Build creates the channels and processes inside one instance under g.
+	// ports holds the channel bound to each declared port in order.
+	Build func(g Globals, ports []interface{})
+}
+
+func NewBlock(name string, ports []Port, build func(g Globals, ports []interface{})) *Block {
+	return &Block{
+		Name:  name,
+		Ports: ports,
+		Build: build,
+	}
+}
+
+// portType returns the direction and token type of a Sender or Receiver
+func portType(port interface{}) (Direction, reflect.Type, bool) {
+	if port == nil {
+		return Input, nil, false
+	}
+	v := reflect.ValueOf(port)
+	if m := v.MethodByName("Recv"); m.IsValid() && m.Type().NumOut() == 2 {
+		return Input, m.Type().Out(0), true
+	} else if m := v.MethodByName("Send"); m.IsValid() && m.Type().NumIn() > 0 {
+		return Output, m.Type().In(0), true
+	}
+	return Input, nil, false
+}
+
+// check panics if the channels don't match the declared ports
+func (b *Block) check(ports []interface{}) {
+	if len(ports) != len(b.Ports) {
+		panic(fmt.Errorf("%w: block %s has %d ports, found %d", Misconfigured, b.Name, len(b.Ports), len(ports)))
+	}
+	for i, port := range ports {
+		dir, typ, ok := portType(port)
+		if !ok || dir != b.Ports[i].Dir || typ != b.Ports[i].Type {
+			panic(fmt.Errorf("%w: port %s of block %s expects an %s of %v", Misconfigured, b.Ports[i].Name, b.Name, b.Ports[i].Dir, b.Ports[i].Type))
+		}
+	}
+}
+
+// Run instantiates the block in g, usually created with Sub, and waits for
+// the processes inside it like any other process
+func (b *Block) Run(g Globals, ports ...interface{}) {
+	b.check(ports)
+	g.Init()
+	defer g.Done()
+
+	if gl, ok := g.(*globals); ok {
+		gl.monitor.mu.Lock()
+		gl.block = b
+		gl.bindings = ports
+		gl.monitor.mu.Unlock()
+	}
+
+	b.Build(g, ports)
+}
@@ -0,0 +1,91 @@
+package chp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/param"
+)
+
+// pipe is two buffers in a row
+var pipe = NewBlock("Pipe", []Port{In[int64]("L"), Out[int64]("R")}, func(g Globals, ports []interface{}) {
+	L := ports[0].(Receiver[int64])
+	R := ports[1].(Sender[int64])
+
+	Ms, Mr := Chan[int64]("M", 0)
+	go Buffer(g.Sub("b0"), L, Ms)
+	go Buffer(g.Sub("b1"), Mr, R)
+})
+
+func TestIntegrationBlock(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/block")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+
+	Ls, Lr := Chan[int64]("L", 0)
+	Xs, Xr := Chan[int64]("X", 0)
+	Rs, Rr := Chan[int64]("R", 0)
+	Vs, Vr := Chan[int64]("V", 4)
+
+	src := g.Sub("src")
+	go SourceN(100, RandomInt64(src.Rand(), -1000, 1000), src, Ls, Vs)
+	go pipe.Run(g.Sub("p0"), Lr, Xs)
+	go pipe.Run(g.Sub("p1"), Xr, Rs)
+	go SinkAndCheck(AreEqual[int64], g.Sub("sink"), Rr, Vr)
+	g.Done()
+
+	n := g.Netlist()
+	var names []string
+	for _, p := range n.Processes {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"top", "top.p0", "top.p0.b0", "top.p0.b1", "top.p1", "top.p1.b0", "top.p1.b1", "top.sink", "top.src"}, names)
+
+	p1 := n.Processes[4]
+	assert.Equal(t, "Pipe", p1.Block)
+	assert.Equal(t, "top", p1.Parent)
+	assert.Len(t, p1.Ports, 2)
+	assert.Equal(t, "L", p1.Ports[0].Name)
+	assert.Equal(t, Input, p1.Ports[0].Dir)
+	assert.Equal(t, "int64", p1.Ports[0].Type)
+	x := n.Channels[p1.Ports[0].Channel]
+	assert.Equal(t, NetChannel{"X", "int64", "top.p0.b1", "top.p1.b0"}, x)
+
+	// each instance has its own internal channel
+	var internal []NetChannel
+	for _, c := range n.Channels {
+		if c.Name == "M" {
+			internal = append(internal, c)
+		}
+	}
+	assert.Equal(t, []NetChannel{
+		{"M", "int64", "top.p0.b0", "top.p0.b1"},
+		{"M", "int64", "top.p1.b0", "top.p1.b1"},
+	}, internal)
+
+	assert.Contains(t, n.String(), "\ttop.p1\tPipe\n\t\tinput L int64\tX\n")
+}
+
+func TestUnitBlockPorts(t *testing.T) {
+	_, Lr := Chan[int64]("L", 0)
+	Rs, _ := Chan[int64]("R", 0)
+	Bs, _ := Chan[bool]("B", 0)
+
+	assert.NotPanics(t, func() { pipe.check([]interface{}{Lr, Rs}) })
+
+	misconfigured := func(ports ...interface{}) {
+		defer func() {
+			err, _ := recover().(error)
+			assert.True(t, errors.Is(err, Misconfigured))
+		}()
+		pipe.check(ports)
+	}
+	misconfigured(Lr)
+	misconfigured(Rs, Lr)
+	misconfigured(Lr, Bs)
+	misconfigured(Lr, nil)
+}
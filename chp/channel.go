@@ -10,6 +10,8 @@ import (
 	"reflect"
 	
 	"git.broccolimicro.io/Broccoli/pr.git/chp/timing"
+	"git.broccolimicro.io/Broccoli/pr.git/chp/trace"
+	"git.broccolimicro.io/Broccoli/pr.git/chp/vcd"
 )
 
 type Void struct {}
@@ -134,14 +136,20 @@ func Recover[T interface{}](c chan T) {
 	}
 }
 
+// OnAction, OnSignal and On run their blocking operations like Offer and
+// Expect do. Under the deterministic kernel On is scheduled with the
+// process that owns its ports. OnAction and OnSignal only see a function,
+// they join the deterministic simulation in progress and fall back to a
+// plain goroutine when several run at once.
+
 func OnAction[T interface{}](op func(args ...float64) (T, float64), args ...float64) timing.Action[T] {
 	var send timing.Action[T] = make(chan timing.Value[T], 1)
 
-	go func() {
+	asyncAny(send.C(), func() {
 		defer Recover(send)
 		v, t := op(args...)
 		send <- timing.Value[T]{t, v}
-	}()
+	})
 
 	return send
 }
@@ -149,18 +157,47 @@ func OnAction[T interface{}](op func(args ...float64) (T, float64), args ...floa
 func OnSignal(op func(args ...float64) float64, args ...float64) timing.Signal {
 	var send timing.Signal = make(chan float64, 1)
 
-	go func() {
+	asyncAny(send.C(), func() {
 		defer Recover(send)
 		send <- op(args...)
-	}()
+	})
 
 	return send
 }
 
+// portGlobals returns the process that owns the first port, nil if none of
+// them has one yet
+func portGlobals(ports []interface{}) Globals {
+	for _, b := range ports {
+		items := []interface{}{b}
+		if _, ok := b.(Waiter); !ok && (reflect.TypeOf(b).Kind() == reflect.Slice || reflect.TypeOf(b).Kind() == reflect.Array) {
+			items = nil
+			v := reflect.ValueOf(b)
+			for i := 0; i < v.Len(); i++ {
+				items = append(items, v.Index(i).Interface())
+			}
+		}
+		for _, item := range items {
+			gd, ok := guardOf(item)
+			if !ok {
+				continue
+			}
+			c, send := gd.endpoint()
+			s, r := c.endpoints()
+			if send && s != nil {
+				return s
+			} else if !send && r != nil {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
 func On(ports ...interface{}) timing.Signal {
 	var send timing.Signal = make(chan float64, 1)
 
-	go func() {
+	fn := func() {
 		defer Recover(send)
 		t_o := timing.Max()
 		for _, b := range ports {
@@ -181,7 +218,12 @@ func On(ports ...interface{}) timing.Signal {
 			}
 		}
 		send <- t_o.Get()
-	}()
+	}
+	if g := portGlobals(ports); g != nil {
+		async(g, send.C(), fn)
+	} else {
+		asyncAny(send.C(), fn)
+	}
 
 	return send
 }
@@ -199,6 +241,16 @@ type channel[T interface{}] struct {
 	recvMu *sync.Mutex
 
 	cond *sync.Cond
+
+	// the processes on either side, for the deadlock report
+	sender *globals
+	receiver *globals
+	watched bool
+
+	// deterministic scheduler, nil if disabled
+	k *kernel
+	// woken by every change, for Select
+	watchers []chan struct{}
 }
 
 type Logger[T interface{}] interface {
@@ -219,24 +271,7 @@ func Log[T interface{}](filename string) Logger[T] {
 }
 
 func (l *logger[T]) WriteType(t reflect.Type) {
-	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
-		fmt.Fprintf(l.log, "[")
-		l.WriteType(t.Elem())
-		fmt.Fprintf(l.log, "]")
-	} else if t.Kind() == reflect.Struct {
-		fmt.Fprintf(l.log, "{")
-		for i := 0; i < t.NumField(); i++ {
-			if i != 0 {
-				fmt.Fprintf(l.log, " ")
-			}
-			f := t.Field(i)
-			fmt.Fprintf(l.log, "%s:", f.Name)
-			l.WriteType(f.Type)
-		}
-		fmt.Fprintf(l.log, "}")
-	} else {
-		fmt.Fprintf(l.log, "%s", t.Name())
-	}
+	fmt.Fprintf(l.log, "%s", trace.TypeString(t))
 }
 
 func (l *logger[T]) Write(value T, t float64) {
@@ -265,6 +300,29 @@ func (l *logger[T]) Close() error {
 	return nil
 }
 
+// traceToken records a token in the structured trace if there is one
+func traceToken[T interface{}](g Globals, name string, dir trace.Direction, start, end float64, value T) {
+	tr := g.Trace()
+	if tr == nil || name == "" {
+		return
+	}
+
+	var temp T
+	v := trace.Encode(value)
+	err := tr.Write(trace.Event{
+		Process: g.Name(),
+		Channel: name,
+		Dir: dir,
+		Start: start,
+		End: end,
+		Type: trace.TypeString(reflect.TypeOf(temp)),
+		Value: &v,
+	})
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
 type sender[T interface{}] struct {
 	c *channel[T]
 	g Globals
@@ -384,6 +442,56 @@ func BusArr[T interface{}](name string, n int, slack int64) []Channel[T] {
 }
 
 
+// wait blocks until the other side changes the channel, c.cond.L must be
+// held
+func (c *channel[T]) wait() {
+	if c.k == nil {
+		c.cond.Wait()
+		return
+	}
+	c.cond.L.Unlock()
+	c.k.park(c)
+	c.cond.L.Lock()
+}
+
+func (c *channel[T]) signal() {
+	c.cond.Signal()
+	c.wake()
+}
+
+// wake notifies the kernel and any Select waiting on this channel,
+// c.cond.L must be held
+func (c *channel[T]) wake() {
+	for _, w := range c.watchers {
+		select {
+		case w <- struct{}{}:
+		default:
+		}
+	}
+	if c.k != nil {
+		c.k.notify(c)
+	}
+}
+
+// lock acquires the send or receive side of the channel. Under the
+// kernel, only one process runs at a time so it can't block on the mutex.
+func (c *channel[T]) lock(mu *sync.Mutex) {
+	if c.k == nil {
+		mu.Lock()
+		return
+	}
+	for !mu.TryLock() {
+		c.k.park(c)
+	}
+}
+
+func (c *channel[T]) unlock(mu *sync.Mutex) {
+	mu.Unlock()
+	if c.k != nil {
+		c.k.notify(c)
+	}
+}
+
 func (c *channel[T]) full() bool {
 	return c.write == c.read && c.ready
 }
@@ -423,16 +531,17 @@ func (c *channel[T]) incWrite() int {
 }
 
 func (c *channel[T]) BeginSend() bool {
-	c.sendMu.Lock()
+	c.lock(c.sendMu)
 	c.cond.L.Lock()
 	defer c.cond.L.Unlock()
 
 	for c.full() {
 		if c.sendDead() {
-			c.cond.Signal()
+			c.unlock(c.sendMu)
+			c.signal()
 			return false
 		}
-		c.cond.Wait()
+		c.wait()
 	}
 
 	return true
@@ -440,16 +549,16 @@ func (c *channel[T]) BeginSend() bool {
 
 func (c *channel[T]) EndSend() (float64, bool) {
 	c.cond.L.Lock()
-	defer c.sendMu.Unlock()
+	defer c.unlock(c.sendMu)
 	defer c.cond.L.Unlock()
 
 	i := c.incWrite()
-	c.cond.Signal()
+	c.signal()
 	for c.full() {
 		if c.sendDead() {
 			return c.buffer[i].T, false
 		}
-		c.cond.Wait()
+		c.wait()
 	}
 	if c.readyTime > c.buffer[i].T {
 			c.buffer[i].T = c.readyTime
@@ -459,16 +568,17 @@ func (c *channel[T]) EndSend() (float64, bool) {
 }
 
 func (c *channel[T]) BeginRecv() bool {
-	c.recvMu.Lock()
+	c.lock(c.recvMu)
 	c.cond.L.Lock()
 	defer c.cond.L.Unlock()
 
 	for c.empty() {
 		if c.recvDead() {
-			c.cond.Signal()
+			c.unlock(c.recvMu)
+			c.signal()
 			return false
 		}
-		c.cond.Wait()
+		c.wait()
 	}
 
 	return true
@@ -476,23 +586,23 @@ func (c *channel[T]) BeginRecv() bool {
 
 func (c *channel[T]) EndRecv(t float64) bool {
 	c.cond.L.Lock()
-	defer c.recvMu.Unlock()
+	defer c.unlock(c.recvMu)
 	defer c.cond.L.Unlock()
 
 	if c.recvDead() {
-		c.cond.Signal()
+		c.signal()
 		return false
 	}
 
 	c.incRead(t)
 
-	c.cond.Signal()
+	c.signal()
 	return true
 }
 
 func (c *channel[T]) EndWait(t float64) (float64, bool) {
 	c.cond.L.Lock()
-	defer c.sendMu.Unlock()
+	defer c.unlock(c.sendMu)
 	defer c.cond.L.Unlock()
 
 	if c.readyTime > t {
@@ -500,25 +610,25 @@ func (c *channel[T]) EndWait(t float64) (float64, bool) {
 	}
 
 	if c.sendDead() {
-		c.cond.Signal()
+		c.signal()
 		return t, false
 	}
 
-	c.cond.Signal()
+	c.signal()
 	return t, true
 }
 
 func (c *channel[T]) EndProbe() bool {
 	c.cond.L.Lock()
-	defer c.recvMu.Unlock()
+	defer c.unlock(c.recvMu)
 	defer c.cond.L.Unlock()
 
 	if c.recvDead() {
-		c.cond.Signal()
+		c.signal()
 		return false
 	}
 
-	c.cond.Signal()
+	c.signal()
 	return true
 }
 
@@ -536,11 +646,76 @@ func (c *channel[T]) Valid() bool {
 	return !c.empty()
 }
 
+// setEndpoint records the process on one side of the channel and registers
+// the channel with the deadlock monitor
+func (c *channel[T]) setEndpoint(g Globals, send bool) {
+	gl, ok := g.(*globals)
+	if !ok || gl.monitor == nil {
+		return
+	}
+
+	c.cond.L.Lock()
+	if send {
+		c.sender = gl
+	} else {
+		c.receiver = gl
+	}
+	if gl.kernel != nil {
+		c.k = gl.kernel
+	}
+	register := !c.watched
+	c.watched = true
+	c.cond.L.Unlock()
+
+	if register {
+		gl.monitor.watch(c)
+	}
+}
+
+func (c *channel[T]) chanName() string {
+	if c.name == "" {
+		return "<unnamed>"
+	}
+	return c.name
+}
+
+func (c *channel[T]) typeName() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
+
+func (c *channel[T]) endpoints() (*globals, *globals) {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	return c.sender, c.receiver
+}
+
+func (c *channel[T]) snapshot() chanState {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	return chanState{
+		full: c.full(),
+		empty: c.empty(),
+		sendClosed: c.sendBlocked,
+		recvClosed: c.recvBlocked,
+	}
+}
+
+// abort wakes both sides of the channel with a deadlock
+func (c *channel[T]) abort() {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	c.sendBlocked = true
+	c.recvBlocked = true
+	c.cond.Broadcast()
+	c.wake()
+}
+
 func (s *sender[T]) SetGlobals(g Globals) {
 	if s.g != nil {
 		panic(Misconfigured)
 	}
 	s.g = g
+	s.c.setEndpoint(g, true)
 	if s.c.name != "" {
 		s.log = Log[T](filepath.Join(g.Dir(), g.Name()+"."+s.c.name+".s"))
 	}
@@ -556,12 +731,22 @@ func (s *sender[T]) Send(value T, args ...float64) float64 {
 		start += args[0]
 	}
 
+	orderEvent(s.g, start)
 	s.g.Timing()
 	if s.g.Debug() && s.c.name != "" {
 		fmt.Printf("%f ns\t\t%s!%v\t\t%s\n", start, s.c.name, value, s.g.Name())
 	}
 
+	var wave []string
+	if w := s.g.VCD(); w != nil && s.c.name != "" {
+		wave = vcd.Scope(s.g.Name(), s.c.name)
+		w.Toggle(append(wave, "req"), start)
+		w.Value(wave, start, value)
+	}
+
+	op := beginOp(s.g, s.c, true, "send")
 	if !s.c.BeginSend() {
+		failOp(s.g, op)
 		panic(timing.Deadlock)
 	}
 
@@ -570,12 +755,18 @@ func (s *sender[T]) Send(value T, args ...float64) float64 {
 	s.g.Timing()
 	t, ok := s.c.EndSend()
 	if !ok {
+		failOp(s.g, op)
 		panic(timing.Deadlock)
 	}
+	endOp(s.g, op)
 
 	if s.log != nil {
 		s.log.Write(value, t)
 	}
+	traceToken(s.g, s.c.name, trace.Send, start, t, value)
+	if w := s.g.VCD(); w != nil && wave != nil {
+		w.Toggle(append(wave, "ack"), t)
+	}
 
 	if s.g.Debug() && s.c.name != "" {
 		fmt.Printf("%f ns\t\t  %s¡\t\t%s\n", t, s.c.name, s.g.Name())
@@ -587,10 +778,10 @@ func (s *sender[T]) Send(value T, args ...float64) float64 {
 func (s *sender[T]) Offer(value T, args ...float64) timing.Signal {
 	var send timing.Signal = make(chan float64, 1)
 
-	go func() {
+	async(s.g, send.C(), func() {
 		defer Recover(chan float64(send))
 		send <- s.Send(value, args...)
-	}()
+	})
 
 	return send
 }
@@ -598,10 +789,10 @@ func (s *sender[T]) Offer(value T, args ...float64) timing.Signal {
 func (s *sender[T]) Watch(args ...float64) timing.Signal {
 	var send timing.Signal = make(chan float64, 1)
 
-	go func() {
+	async(s.g, send.C(), func() {
 		defer Recover(send)
 		send <- s.Wait(args...)
-	}()
+	})
 
 	return send
 }
@@ -611,6 +802,7 @@ func (s *sender[T]) Ready() bool {
 		panic(fmt.Errorf("you must call g.Init for this sender"))
 	}
 
+	orderEvent(s.g, s.g.Curr())
 	s.g.Timing()
 	rdy := s.c.Ready()
 	if s.g.Debug() && s.c.name != "" {
@@ -634,20 +826,25 @@ func (s *sender[T]) Wait(args ...float64) float64 {
 		start += args[0]
 	}
 
+	orderEvent(s.g, start)
 	s.g.Timing()
 	if s.g.Debug() && s.c.name != "" {
 		fmt.Printf("%f ns\t\t#%s!\t\t%s\n", start, s.c.name, s.g.Name())
 	}
 	
+	op := beginOp(s.g, s.c, true, "wait")
 	if !s.c.BeginSend() {
+		failOp(s.g, op)
 		panic(timing.Deadlock)
 	}
 	
 	s.g.Timing()
 	t, ok := s.c.EndWait(start)
 	if !ok {
+		failOp(s.g, op)
 		panic(timing.Deadlock)
 	}
+	endOp(s.g, op)
 
 	if s.g.Debug() && s.c.name != "" {
 		fmt.Printf("%f ns\t\t  #%s¡\t\t%s\n", t, s.c.name, s.g.Name())
@@ -667,7 +864,7 @@ func (s *sender[T]) Close() error {
 		s.log = nil
 	}
 	s.c.sendBlocked = true
-	s.c.cond.Signal()
+	s.c.signal()
 	return nil
 }
 
@@ -676,6 +873,7 @@ func (r *receiver[T]) SetGlobals(g Globals) {
 		panic(Misconfigured)
 	}
 	r.g = g
+	r.c.setEndpoint(g, false)
 	if r.c.name != "" {
 		r.log = Log[T](filepath.Join(g.Dir(), g.Name()+"."+r.c.name+".r"))
 	}
@@ -691,12 +889,15 @@ func (r *receiver[T]) Recv(args ...float64) (T, float64) {
 		start += args[0]
 	}
 
+	orderEvent(r.g, start)
 	r.g.Timing()
 	if r.g.Debug() && r.c.name != "" {
 		fmt.Printf("%f ns\t\t%s?\t\t%s\n", start, r.c.name, r.g.Name())
 	}
 
+	op := beginOp(r.g, r.c, false, "recv")
 	if !r.c.BeginRecv() {
+		failOp(r.g, op)
 		panic(timing.Deadlock)
 	}
 	
@@ -705,15 +906,20 @@ func (r *receiver[T]) Recv(args ...float64) (T, float64) {
 		result.T = start
 	}
 
-	if !r.logged && r.log != nil {
-		r.log.Write(result.V, result.T)
+	if !r.logged {
+		if r.log != nil {
+			r.log.Write(result.V, result.T)
+		}
+		traceToken(r.g, r.c.name, trace.Recv, start, result.T, result.V)
 	}
 	r.logged = false
 
 	r.g.Timing()
 	if !r.c.EndRecv(result.T) {
+		failOp(r.g, op)
 		panic(timing.Deadlock)
 	}
+	endOp(r.g, op)
 
 	if r.g.Debug() && r.c.name != "" {
 		fmt.Printf("%f ns\t\t  %s¿%v\t\t%s\n", result.T, r.c.name, result.V, r.g.Name())
@@ -725,11 +931,11 @@ func (r *receiver[T]) Recv(args ...float64) (T, float64) {
 func (r *receiver[T]) Expect(args ...float64) timing.Action[T] {
 	var recv timing.Action[T] = make(chan timing.Value[T], 1)
 
-	go func() {
+	async(r.g, recv.C(), func() {
 		defer Recover(chan timing.Value[T](recv))
 		v, t := r.Recv(args...)
 		recv <- timing.Value[T]{t, v}
-	}()
+	})
 
 	return recv
 }
@@ -737,11 +943,11 @@ func (r *receiver[T]) Expect(args ...float64) timing.Action[T] {
 func (r *receiver[T]) Read(args ...float64) timing.Action[T] {
 	var recv timing.Action[T] = make(chan timing.Value[T], 1)
 
-	go func() {
+	async(r.g, recv.C(), func() {
 		defer Recover(recv)
 		v, t := r.Probe(args...)
 		recv <- timing.Value[T]{t, v}
-	}()
+	})
 
 	return recv
 }
@@ -751,6 +957,7 @@ func (r *receiver[T]) Valid() bool {
 		panic(fmt.Errorf("you must call g.Init for this receiver"))
 	}
 
+	orderEvent(r.g, r.g.Curr())
 	r.g.Timing()
 	val := r.c.Valid()
 	if r.g.Debug() && r.c.name != "" {
@@ -774,12 +981,15 @@ func (r *receiver[T]) Probe(args ...float64) (T, float64) {
 		start += args[0]
 	}
 
+	orderEvent(r.g, start)
 	r.g.Timing()
 	if r.g.Debug() && r.c.name != "" {
 		fmt.Printf("%f ns\t\t#%s?\t\t%s\n", start, r.c.name, r.g.Name())
 	}
 
+	op := beginOp(r.g, r.c, false, "probe")
 	if !r.c.BeginRecv() {
+		failOp(r.g, op)
 		panic(timing.Deadlock)
 	}
 
@@ -788,15 +998,20 @@ func (r *receiver[T]) Probe(args ...float64) (T, float64) {
 		result.T = start
 	}
 
-	if !r.logged && r.log != nil {
-		r.log.Write(result.V, result.T)
+	if !r.logged {
+		if r.log != nil {
+			r.log.Write(result.V, result.T)
+		}
+		traceToken(r.g, r.c.name, trace.Recv, start, result.T, result.V)
 	}
 	r.logged = true
 
 	r.g.Timing()
 	if !r.c.EndProbe() {
+		failOp(r.g, op)
 		panic(timing.Deadlock)
 	}
+	endOp(r.g, op)
 
 	if r.g.Debug() && r.c.name != "" {
 		fmt.Printf("%f ns\t\t  #%s¿%v\t\t%s\n", result.T, r.c.name, result.V, r.g.Name())
@@ -821,7 +1036,7 @@ func (r *receiver[T]) Close() error {
 		r.log = nil
 	}
 	r.c.recvBlocked = true
-	r.c.cond.Signal()
+	r.c.signal()
 	return nil
 }
 
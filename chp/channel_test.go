@@ -1,11 +1,14 @@
 package chp
 
 import (
+	"os"
+	"path/filepath"
 	"time"
 	"testing"
 	"github.com/stretchr/testify/assert"
 
 	"git.broccolimicro.io/Broccoli/pr.git/chp/param"
+	"git.broccolimicro.io/Broccoli/pr.git/chp/trace"
 )
 
 func TestUnitSendRecv(t *testing.T) {
@@ -43,3 +46,54 @@ func TestUnitSendRecv(t *testing.T) {
 	}
 }
 
+
+func TestIntegrationTrace(t *testing.T) {
+	out := param.String(2, "test/chp/trace")
+
+	g, err := New(out)
+	assert.NoError(t, err)
+	g.SetTrace(trace.JSON)
+
+	Ls, Lr := Chan[int64]("L", 0)
+
+	src := g.Sub("src")
+	go SourceN(10, RandomInt64(src.Rand(), 0, 2), src, Ls)
+	go SinkN(10, g.Sub("sink"), Lr)
+	g.Done()
+
+	events, err := trace.Load(trace.Find(out))
+	assert.NoError(t, err)
+
+	count := map[trace.Direction]int{}
+	for _, e := range events {
+		count[e.Dir]++
+		if e.Dir != trace.Cycle {
+			assert.Equal(t, "L", e.Channel)
+			assert.Equal(t, "int64", e.Type)
+			assert.Equal(t, trace.Int, e.Value.Kind)
+			assert.LessOrEqual(t, e.Start, e.End)
+		}
+	}
+	assert.Equal(t, 10, count[trace.Send])
+	assert.Equal(t, 10, count[trace.Recv])
+}
+
+func TestIntegrationVCD(t *testing.T) {
+	out := param.String(2, "test/chp/vcd")
+
+	g, err := New(out)
+	assert.NoError(t, err)
+	g.SetVCD(true)
+
+	Ls, Lr := Chan[int64]("L", 0)
+
+	src := g.Sub("src")
+	go SourceN(10, RandomInt64(src.Rand(), 0, 2), src, Ls)
+	go SinkN(10, g.Sub("sink"), Lr)
+	g.Done()
+
+	data, err := os.ReadFile(filepath.Join(out, "top.vcd"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "$scope module src $end\n$scope module L $end\n")
+	assert.Contains(t, string(data), "$var wire 64 \" data $end\n")
+}
@@ -2,6 +2,8 @@ package chp
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
 
 	"git.broccolimicro.io/Broccoli/pr.git/chp/timing"
 )
@@ -10,12 +12,12 @@ func Connect[T interface{}](g Globals, L Receiver[T], R Sender[T]) {
 	p := g.Init(L, R)
 	defer g.Done()
 
-	d0L := p.Find("d0L")
-	d0R := p.Find("d0R")
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
 		t0 := R.Wait()
 		x, tl := L.Recv(t0, d0L)
 		tr := R.Send(x, tl+d0R)
@@ -28,12 +30,12 @@ func Buffer[T interface{}](g Globals, L Receiver[T], R Sender[T]) {
 	p := g.Init(L, R)
 	defer g.Done()
 
-	d0L := p.Find("d0L")
-	d0R := p.Find("d0R")
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
 		x, tl := L.Recv(d0L)
 		tr := R.Send(x, tl+d0R)
 
@@ -45,12 +47,12 @@ func Copy[T interface{}](g Globals, L Receiver[T], R []Sender[T]) {
 	p := g.Init(L, R)
 	defer g.Done()
 
-	d0L := p.Find("d0L")
-	d0R := p.Find("d0R")
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")*float64(len(R))
-
 	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")*float64(len(R))
+
 		x, tl := L.Recv(d0L)
 		tr := timing.Max(tl)
 		for _, r := range R { 
@@ -65,13 +67,13 @@ func Split[T interface{}](g Globals, C Receiver[int], L Receiver[T], R []Sender[
 	p := g.Init(C, L, R)
 	defer g.Done()
 
-	d0C := p.Find("d0C")
-	d0L := p.Find("d0L")
-	d0R := p.Find("d0R")
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	for {
+		d0C := p.Sample("d0C")
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
 		pc := C.Expect(d0C)
 		pl := L.Expect(d0L)
 
@@ -92,13 +94,13 @@ func Merge[T interface{}](g Globals, C Receiver[int], L []Receiver[T], R Sender[
 	p := g.Init(C, L, R)
 	defer g.Done()
 
-	d0C := p.Find("d0C")
-	d0L := p.Find("d0L")
-	d0R := p.Find("d0R")
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	for {
+		d0C := p.Sample("d0C")
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
 		c, tc := C.Recv(d0C)
 		if c < 0 || c >= len(L) {
 			panic(errors.New("merge control channel out of bounds"))
@@ -111,14 +113,690 @@ func Merge[T interface{}](g Globals, C Receiver[int], L []Receiver[T], R Sender[
 	}
 }
 
+// Arbiter forwards tokens from L to R in the order they arrive, using the
+// policy to choose between requests that are waiting at the same time. If C
+// isn't nil, the index of each granted input is sent on it as well. Requests
+// that race each other take an extra dm to resolve metastability.
+func Arbiter[T interface{}](policy Policy, g Globals, L []Receiver[T], R Sender[T], C Sender[int]) {
+	p := g.Init(L, R, C)
+	defer g.Done()
+
+	G := guards(L)
+	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		dm := p.Sample("dm")
+		e0 := p.Sample("e0")
+
+		i, ta, contested := arbitrate(g, policy, G)
+		if contested > 0 {
+			ta += dm
+		}
+		x, tl := L[i].Recv(ta+d0L)
+		tr := timing.Max(R.Send(x, tl+d0R))
+		if C != nil {
+			tr.Add(C.Send(i, tl+d0R))
+		}
+
+		g.Cycle(e0, tl, tr.Get()+d0)
+	}
+}
+
+// Mutex grants exclusive access to one client at a time. Client i acquires
+// by sending on A[i] and releases by sending on R[i]. Requests are granted in
+// the order they arrive, using the policy to choose between requests that
+// are waiting at the same time, and requests that race each other take an
+// extra dm to resolve metastability.
+func Mutex(policy Policy, g Globals, A []Receiver[Void], R []Receiver[Void]) {
+	p := g.Init(A, R)
+	defer g.Done()
+
+	G := guards(A)
+	for {
+		d0A := p.Sample("d0A")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		dm := p.Sample("dm")
+		e0 := p.Sample("e0")
+
+		i, ta, contested := arbitrate(g, policy, G)
+		if contested > 0 {
+			ta += dm
+		}
+		_, tg := A[i].Recv(ta+d0A)
+		_, tr := R[i].Recv(tg+d0R)
+
+		g.Cycle(e0, tg, tr+d0)
+	}
+}
+
+// Func applies fn to each token from L and sends the result on R. If cost
+// isn't nil, it returns the extra delay and energy of computing a value on
+// top of the profile, for example for a carry chain that depends on the
+// operands.
+func Func[A, B interface{}](fn func(A) B, cost func(A) (float64, float64), g Globals, L Receiver[A], R Sender[B]) {
+	p := g.Init(L, R)
+	defer g.Done()
+
+	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		x, tl := L.Recv(d0L)
+		var d, e float64
+		if cost != nil {
+			d, e = cost(x)
+		}
+		tr := R.Send(fn(x), tl+d0R+d)
+
+		g.Cycle(e0+e, tl, tr+d0)
+	}
+}
+
+// Func2 waits for a token on both La and Lb, applies fn and sends the result
+// on R
+func Func2[A, B, C interface{}](fn func(A, B) C, cost func(A, B) (float64, float64), g Globals, La Receiver[A], Lb Receiver[B], R Sender[C]) {
+	p := g.Init(La, Lb, R)
+	defer g.Done()
+
+	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		a, ta := La.Recv(d0L)
+		b, tb := Lb.Recv(d0L)
+		tl := timing.Max(ta, tb).Get()
+		var d, e float64
+		if cost != nil {
+			d, e = cost(a, b)
+		}
+		tr := R.Send(fn(a, b), tl+d0R+d)
+
+		g.Cycle(e0+e, tl, tr+d0)
+	}
+}
+
+// FuncN waits for a token on every input, applies fn to all of them in order
+// and sends the result on R
+func FuncN[A, B interface{}](fn func([]A) B, cost func([]A) (float64, float64), g Globals, L []Receiver[A], R Sender[B]) {
+	p := g.Init(L, R)
+	defer g.Done()
+
+	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		values := make([]A, len(L))
+		t := timing.Max()
+		for j := 0; j < len(L); j++ {
+			var tj float64
+			values[j], tj = L[j].Recv(d0L)
+			t.Add(tj)
+		}
+		tl := t.Get()
+		var d, e float64
+		if cost != nil {
+			d, e = cost(values)
+		}
+		tr := R.Send(fn(values), tl+d0R+d)
+
+		g.Cycle(e0+e, tl, tr+d0)
+	}
+}
+
+// reduceTree combines values with a balanced tree of fn, returning the
+// delay along the deepest path and the total energy reported by cost
+func reduceTree[T interface{}](fn func(T, T) T, cost func(T, T) (float64, float64), values []T) (T, float64, float64) {
+	if len(values) == 1 {
+		return values[0], 0, 0
+	}
+
+	half := (len(values)+1)/2
+	a, da, ea := reduceTree(fn, cost, values[0:half])
+	b, db, eb := reduceTree(fn, cost, values[half:])
+	var d, e float64
+	if cost != nil {
+		d, e = cost(a, b)
+	}
+	if db > da {
+		da = db
+	}
+	return fn(a, b), da+d, ea+eb+e
+}
+
+// Reduce waits for a token on every input and combines them with a balanced
+// tree of fn, like an adder tree. cost is applied to each node of the tree,
+// its delays add up along the deepest path and its energies add up over
+// the whole tree. It needs at least one input.
+func Reduce[T interface{}](fn func(T, T) T, cost func(T, T) (float64, float64), g Globals, L []Receiver[T], R Sender[T]) {
+	if len(L) == 0 {
+		panic(Misconfigured)
+	}
+
+	p := g.Init(L, R)
+	defer g.Done()
+
+	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		values := make([]T, len(L))
+		t := timing.Max()
+		for j := 0; j < len(L); j++ {
+			var tj float64
+			values[j], tj = L[j].Recv(d0L)
+			t.Add(tj)
+		}
+		tl := t.Get()
+		x, d, e := reduceTree(fn, cost, values)
+		tr := R.Send(x, tl+d0R+d)
+
+		g.Cycle(e0+e, tl, tr+d0)
+	}
+}
+
+// Memory holds the words in data, which it modifies. A read receives an
+// address on RA and sends the word on R, a write receives an address on WA
+// and the word on W. Reads and writes are served in the order they arrive.
+func Memory[T interface{}](data []T, g Globals, RA Receiver[int], R Sender[T], WA Receiver[int], W Receiver[T]) {
+	p := g.Init(RA, R, WA, W)
+	defer g.Done()
+
+	for {
+		d0A := p.Sample("d0A")
+		d0W := p.Sample("d0W")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0R := p.Sample("e0R")
+		e0W := p.Sample("e0W")
+
+		i, ta := Select(g, RA, WA)
+		if i == 0 {
+			a, tl := RA.Recv(ta+d0A)
+			if a < 0 || a >= len(data) {
+				panic(errors.New("memory read address out of bounds"))
+			}
+			tr := R.Send(data[a], tl+d0R)
+
+			g.Cycle(e0R, tl, tr+d0)
+		} else {
+			a, tw := WA.Recv(ta+d0A)
+			if a < 0 || a >= len(data) {
+				panic(errors.New("memory write address out of bounds"))
+			}
+			x, tl := W.Recv(d0W)
+			t := timing.Max(tw, tl).Get()
+			data[a] = x
+
+			g.Cycle(e0W, t, t+d0)
+		}
+	}
+}
+
+// Register holds a single value starting at init. A request on Q reads the
+// value onto R and a token on W overwrites it, in the order they arrive.
+func Register[T interface{}](init T, g Globals, Q Receiver[Void], R Sender[T], W Receiver[T]) {
+	p := g.Init(Q, R, W)
+	defer g.Done()
+
+	x := init
+	for {
+		d0Q := p.Sample("d0Q")
+		d0W := p.Sample("d0W")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0R := p.Sample("e0R")
+		e0W := p.Sample("e0W")
+
+		i, ta := Select(g, Q, W)
+		if i == 0 {
+			_, tl := Q.Recv(ta+d0Q)
+			tr := R.Send(x, tl+d0R)
+
+			g.Cycle(e0R, tl, tr+d0)
+		} else {
+			var tl float64
+			x, tl = W.Recv(ta+d0W)
+
+			g.Cycle(e0W, tl, tl+d0)
+		}
+	}
+}
+
+// Counter receives a trip count n on N and sends the loop indices 0 to n-1
+// on R. If Last isn't nil, it is sent whether each index is the last one.
+func Counter(g Globals, N Receiver[int], R Sender[int], Last Sender[bool]) {
+	p := g.Init(N, R, Last)
+	defer g.Done()
+
+	for {
+		d0N := p.Sample("d0N")
+
+		n, t := N.Recv(d0N)
+		for i := 0; i < n; i++ {
+			d0R := p.Sample("d0R")
+			d0 := p.Sample("d0")
+			e0 := p.Sample("e0")
+
+			tr := timing.Max(R.Send(i, t+d0R))
+			if Last != nil {
+				tr.Add(Last.Send(i == n-1, t+d0R))
+			}
+
+			g.Cycle(e0, t, tr.Get()+d0)
+			t = 0
+		}
+	}
+}
+
+// Accumulator folds each token from L into its state with fn, starting from
+// init. A token on C goes with each token from L, when it is true the result
+// is sent on R and the state is reset to init.
+func Accumulator[T interface{}](fn func(T, T) T, init T, g Globals, C Receiver[bool], L Receiver[T], R Sender[T]) {
+	p := g.Init(C, L, R)
+	defer g.Done()
+
+	acc := init
+	for {
+		d0C := p.Sample("d0C")
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		x, tl := L.Recv(d0L)
+		c, tc := C.Recv(d0C)
+		t := timing.Max(tc, tl).Get()
+		acc = fn(acc, x)
+		if c {
+			tr := R.Send(acc, t+d0R)
+			acc = init
+
+			g.Cycle(e0, t, tr+d0)
+		} else {
+			g.Cycle(e0, t, t+d0)
+		}
+	}
+}
+
+// Fifo is a chain of buffer stages from L to R. The number of stages comes
+// from "depth" in the profile rather than the slack of the channels, each
+// stage forwarding a token after "df" and becoming ready for the next one
+// "db" after that.
+func Fifo[T interface{}](g Globals, L Receiver[T], R Sender[T]) {
+	p := g.Init()
+	defer g.Done()
+
+	depth := int(p.Find("depth"))
+	if depth < 1 {
+		depth = 1
+	}
+
+	Ss, Sr := ChanArr[T]("S", depth-1, 0)
+	for i := 0; i < depth; i++ {
+		l, r := L, R
+		if i > 0 {
+			l = Sr[i-1]
+		}
+		if i < depth-1 {
+			r = Ss[i]
+		}
+		go fifoStage(p, g.Sub("stage.%d", i), l, r)
+	}
+}
+
+// the stages of a fifo share its profile but each draws its own samples
+func fifoStage[T interface{}](p timing.Profile, g Globals, L Receiver[T], R Sender[T]) {
+	g.Init(L, R)
+	defer g.Done()
+
+	p = timing.WithRand(p, g.Rand())
+	for {
+		df := p.Sample("df")
+		db := p.Sample("db")
+		e0 := p.Sample("e0")
+
+		x, tl := L.Recv()
+		tr := R.Send(x, tl+df)
+
+		g.Cycle(e0, tl, tr+db)
+	}
+}
+
+// Filter forwards a token from L to R when it passes and discards it
+// otherwise. If pred isn't nil, the token must satisfy it. If C isn't nil, a
+// token on C goes with each token from L and must be true.
+func Filter[T interface{}](pred func(T) bool, g Globals, C Receiver[bool], L Receiver[T], R Sender[T]) {
+	p := g.Init(C, L, R)
+	defer g.Done()
+
+	for {
+		d0C := p.Sample("d0C")
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		x, tl := L.Recv(d0L)
+		t := timing.Max(tl)
+		pass := true
+		if C != nil {
+			c, tc := C.Recv(d0C)
+			t.Add(tc)
+			pass = c
+		}
+		if pred != nil && pass {
+			pass = pred(x)
+		}
+
+		if pass {
+			tr := R.Send(x, t.Get()+d0R)
+			g.Cycle(e0, t.Get(), tr+d0)
+		} else {
+			g.Cycle(e0, t.Get(), t.Get()+d0)
+		}
+	}
+}
+
+// Drop discards the first n tokens from L and then acts as a buffer
+func Drop[T interface{}](n int64, g Globals, L Receiver[T], R Sender[T]) {
+	p := g.Init(L, R)
+	defer g.Done()
+
+	for i := int64(0); i < n; i++ {
+		d0L := p.Sample("d0L")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		_, tl := L.Recv(d0L)
+		g.Cycle(e0, tl, tl+d0)
+	}
+
+	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		x, tl := L.Recv(d0L)
+		tr := R.Send(x, tl+d0R)
+
+		g.Cycle(e0, tl, tr+d0)
+	}
+}
+
+// Initial sends the tokens in init on R and then acts as a buffer. This
+// breaks the cycle in a ring pipeline, which would otherwise never start.
+func Initial[T interface{}](init []T, g Globals, L Receiver[T], R Sender[T]) {
+	p := g.Init(L, R)
+	defer g.Done()
+
+	for _, x := range init {
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		tr := R.Send(x, d0R)
+		g.Cycle(e0, 0, tr+d0)
+	}
+
+	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		x, tl := L.Recv(d0L)
+		tr := R.Send(x, tl+d0R)
+
+		g.Cycle(e0, tl, tr+d0)
+	}
+}
+
+// Inject is Initial with a single token
+func Inject[T interface{}](x T, g Globals, L Receiver[T], R Sender[T]) {
+	Initial([]T{x}, g, L, R)
+}
+
+// Demux sends each token from L to the output chosen by route
+func Demux[T interface{}](route func(T) int, g Globals, L Receiver[T], R []Sender[T]) {
+	p := g.Init(L, R)
+	defer g.Done()
+
+	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		x, tl := L.Recv(d0L)
+		c := route(x)
+		if c < 0 || c >= len(R) {
+			panic(errors.New("demux destination out of bounds"))
+		}
+		tr := R[c].Send(x, tl+d0R)
+
+		g.Cycle(e0, tl, tr+d0)
+	}
+}
+
+// Crossbar connects every input to every output. Each token from L[i] goes
+// to the output read from D[i]. The inputs are served concurrently and each
+// output grants the inputs in the order they arrive, taking an extra dm to
+// resolve requests that race each other. The input i and output j are timed
+// by the keys in.<i>.<name> and out.<j>.<name> of the Crossbar's profile,
+// falling back to <name> when they are missing.
+func Crossbar[T interface{}](g Globals, D []Receiver[int], L []Receiver[T], R []Sender[T]) {
+	p := g.Init()
+	defer g.Done()
+
+	if len(D) != len(L) {
+		panic(Misconfigured)
+	}
+
+	X := make([][]Receiver[T], len(R))
+	for i := range L {
+		Xs, Xr := ChanArr[T](fmt.Sprintf("X.%d", i), len(R), 0)
+		for j := range R {
+			X[j] = append(X[j], Xr[j])
+		}
+		go crossbarIn(timing.WithPrefix(p, fmt.Sprintf("in.%d", i)), g.Sub("in.%d", i), D[i], L[i], Xs)
+	}
+	for j := range R {
+		go crossbarOut(timing.WithPrefix(p, fmt.Sprintf("out.%d", j)), g.Sub("out.%d", j), X[j], R[j])
+	}
+}
+
+func crossbarIn[T interface{}](p timing.Profile, g Globals, D Receiver[int], L Receiver[T], X []Sender[T]) {
+	g.Init(D, L, X)
+	defer g.Done()
+
+	p = timing.WithRand(p, g.Rand())
+	for {
+		d0D := p.Sample("d0D")
+		d0L := p.Sample("d0L")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		c, tc := D.Recv(d0D)
+		if c < 0 || c >= len(X) {
+			panic(errors.New("crossbar destination out of bounds"))
+		}
+		x, tl := L.Recv(d0L)
+		t := timing.Max(tc, tl).Get()
+		tx := X[c].Send(x, t)
+
+		g.Cycle(e0, t, tx+d0)
+	}
+}
+
+func crossbarOut[T interface{}](p timing.Profile, g Globals, X []Receiver[T], R Sender[T]) {
+	g.Init(X, R)
+	defer g.Done()
+
+	p = timing.WithRand(p, g.Rand())
+	G := guards(X)
+	for {
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		dm := p.Sample("dm")
+		e0 := p.Sample("e0")
+
+		i, ta, contested := arbitrate(g, FirstCome, G)
+		if contested > 0 {
+			ta += dm
+		}
+		x, tl := X[i].Recv(ta)
+		tr := R.Send(x, tl+d0R)
+
+		g.Cycle(e0, tl, tr+d0)
+	}
+}
+
+// Zip waits for a token on every input and sends them together on R
+func Zip[T interface{}](g Globals, L []Receiver[T], R Sender[[]T]) {
+	p := g.Init(L, R)
+	defer g.Done()
+
+	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		values := make([]T, len(L))
+		t := timing.Max()
+		for j := 0; j < len(L); j++ {
+			var tj float64
+			values[j], tj = L[j].Recv(d0L)
+			t.Add(tj)
+		}
+		tl := t.Get()
+		tr := R.Send(values, tl+d0R)
+
+		g.Cycle(e0, tl, tr+d0)
+	}
+}
+
+// Unzip sends element i of each token from L on R[i]
+func Unzip[T interface{}](g Globals, L Receiver[[]T], R []Sender[T]) {
+	p := g.Init(L, R)
+	defer g.Done()
+
+	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		x, tl := L.Recv(d0L)
+		if len(x) != len(R) {
+			panic(errors.New("unzip token has the wrong length"))
+		}
+		tr := timing.Max(tl)
+		for j, r := range R {
+			tr.Add(r.Send(x[j], tl+d0R))
+		}
+
+		g.Cycle(e0, tl, tr.Get()+d0)
+	}
+}
+
+// fields checks that there is a port for every field of the struct T and
+// returns their Recv or Send methods
+func fields[T interface{}](ports []interface{}, method string) []reflect.Value {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct || typ.NumField() != len(ports) {
+		panic(Misconfigured)
+	}
+
+	result := make([]reflect.Value, len(ports))
+	for i, port := range ports {
+		m := reflect.ValueOf(port).MethodByName(method)
+		if !m.IsValid() {
+			panic(Misconfigured)
+		}
+		if method == "Recv" && m.Type().Out(0) != typ.Field(i).Type {
+			panic(Misconfigured)
+		} else if method == "Send" && m.Type().In(0) != typ.Field(i).Type {
+			panic(Misconfigured)
+		}
+		result[i] = m
+	}
+	return result
+}
+
+// ZipStruct waits for a token on every input and sends them on R as the
+// fields of a struct, in order. L[i] must be a Receiver of the type of
+// field i.
+func ZipStruct[T interface{}](g Globals, L []interface{}, R Sender[T]) {
+	p := g.Init(L, R)
+	defer g.Done()
+
+	recv := fields[T](L, "Recv")
+	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		var x T
+		v := reflect.ValueOf(&x).Elem()
+		t := timing.Max()
+		for j, m := range recv {
+			out := m.Call([]reflect.Value{reflect.ValueOf(d0L)})
+			v.Field(j).Set(out[0])
+			t.Add(out[1].Float())
+		}
+		tl := t.Get()
+		tr := R.Send(x, tl+d0R)
+
+		g.Cycle(e0, tl, tr+d0)
+	}
+}
+
+// UnzipStruct sends field i of each struct from L on R[i], which must be a
+// Sender of the type of that field
+func UnzipStruct[T interface{}](g Globals, L Receiver[T], R []interface{}) {
+	p := g.Init(L, R)
+	defer g.Done()
+
+	send := fields[T](R, "Send")
+	for {
+		d0L := p.Sample("d0L")
+		d0R := p.Sample("d0R")
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
+		x, tl := L.Recv(d0L)
+		v := reflect.ValueOf(x)
+		tr := timing.Max(tl)
+		for j, m := range send {
+			out := m.Call([]reflect.Value{v.Field(j), reflect.ValueOf(tl+d0R)})
+			tr.Add(out[0].Float())
+		}
+
+		g.Cycle(e0, tl, tr.Get()+d0)
+	}
+}
+
 func Source[T interface{}](fn func(i int64) T, g Globals, R ...Sender[T]) {
 	p := g.Init(R)
 	defer g.Done()
 
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")*float64(len(R))
-
 	for i := int64(0); ; i++ {
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")*float64(len(R))
+
 		value := fn(i)
 		t := timing.Max()
 		for j := 0; j < len(R); j++ {
@@ -132,10 +810,10 @@ func SourceN[T interface{}](n int64, fn func(i int64) T, g Globals, R ...Sender[
 	p := g.Init(R)
 	defer g.Done()
 
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")*float64(len(R))
-
 	for i := int64(0); i < n; i++ {
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")*float64(len(R))
+
 		value := fn(i)
 		t := timing.Max()
 		for j := 0; j < len(R); j++ {
@@ -149,10 +827,10 @@ func Sink[T interface{}](g Globals, L Receiver[T]) {
 	p := g.Init(L)
 	defer g.Done()
 
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	for {
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
 		_, tl := L.Recv()
 		g.Cycle(e0, tl, tl+d0)
 	}
@@ -162,10 +840,10 @@ func SinkN[T interface{}](n int64, g Globals, L Receiver[T]) {
 	p := g.Init(L)
 	defer g.Done()
 
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	for i := int64(0); i < n; i++ {
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
 		_, tl := L.Recv()
 		g.Cycle(e0, tl, tl+d0)
 	}
@@ -175,12 +853,12 @@ func SinkAndCheck[T interface{}](valid func(token int64, values []T) error, g Gl
 	p := g.Init(L)
 	defer g.Done()
 
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	var tl float64
 	values := make([]T, len(L))
 	for i := int64(0); ; i++ {
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
 		t := timing.Max()
 		for j := 0; j < len(L); j++ {
 			values[j], tl = L[j].Recv()
@@ -198,12 +876,12 @@ func SinkAndCheckN[T interface{}](n int64, valid func(token int64, values []T) e
 	p := g.Init(L)
 	defer g.Done()
 
-	d0 := p.Find("d0")
-	e0 := p.Find("e0")
-
 	var tl float64
 	values := make([]T, len(L))
 	for i := int64(0); i < n; i++ {
+		d0 := p.Sample("d0")
+		e0 := p.Sample("e0")
+
 		t := timing.Max()
 		for j := 0; j < len(L); j++ {
 			values[j], tl = L[j].Recv()
@@ -1,7 +1,10 @@
 package chp
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 	"github.com/stretchr/testify/assert"
 
 	"git.broccolimicro.io/Broccoli/pr.git/chp/param"
@@ -21,7 +24,8 @@ func TestIntegrationConnect(t *testing.T) {
 	Vs, Vr := Chan[int64]("V", 2)
 	Rs, Rr := Chan[int64]("R", 0)
 
-	go SourceN(100, RandomInt64(min, max), g.Sub("src"), Ls, Vs)
+	src := g.Sub("src")
+	go SourceN(100, RandomInt64(src.Rand(), min, max), src, Ls, Vs)
 	go SinkAndCheck(AreEqual[int64], g.Sub("sink"), Vr, Rr)
 	go Connect(g.Sub("dut"), Lr, Rs)
 }
@@ -40,7 +44,8 @@ func TestIntegrationBuffer(t *testing.T) {
 	Vs, Vr := Chan[int64]("V", 2)
 	Rs, Rr := Chan[int64]("R", 0)
 
-	go SourceN(100, RandomInt64(min, max), g.Sub("src"), Ls, Vs)
+	src := g.Sub("src")
+	go SourceN(100, RandomInt64(src.Rand(), min, max), src, Ls, Vs)
 	go SinkAndCheck(AreEqual[int64], g.Sub("sink"), Vr, Rr)
 	go Buffer(g.Sub("dut"), Lr, Rs)
 }
@@ -59,7 +64,8 @@ func TestIntegrationCopy(t *testing.T) {
 	Ls, Lr := Chan[int64]("L", 0)
 	Rs, Rr := ChanArr[int64]("R", copies, 0)
 
-	go SourceN[int64](100, RandomInt64(min, max), g.Sub("src"), Ls)
+	src := g.Sub("src")
+	go SourceN[int64](100, RandomInt64(src.Rand(), min, max), src, Ls)
 	for i := 0; i < copies; i++ {
 		go Sink[int64](g.Sub("sink.%d", i), Rr[i])
 	}
@@ -81,8 +87,10 @@ func TestIntegrationSplit(t *testing.T) {
 	Ls, Lr := Chan[int64]("L", 0)
 	Rs, Rr := ChanArr[int64]("R", choices, 0)
 
-	go SourceN[int](100, RandomInt(0, choices), g.Sub("src_C"), Cs)
-	go Source[int64](RandomInt64(min, max), g.Sub("src"), Ls)
+	srcC := g.Sub("src_C")
+	go SourceN[int](100, RandomInt(srcC.Rand(), 0, choices), srcC, Cs)
+	src := g.Sub("src")
+	go Source[int64](RandomInt64(src.Rand(), min, max), src, Ls)
 	for i := 0; i < choices; i++ {
 		go Sink[int64](g.Sub("sink.%d", i), Rr[i])
 	}
@@ -104,11 +112,594 @@ func TestIntegrationMerge(t *testing.T) {
 	Ls, Lr := ChanArr[int64]("L", choices, 0)
 	Rs, Rr := Chan[int64]("R", 0)
 
-	go SourceN[int](100, RandomInt(0, choices), g.Sub("src_C"), Cs)
+	srcC := g.Sub("src_C")
+	go SourceN[int](100, RandomInt(srcC.Rand(), 0, choices), srcC, Cs)
 	for i := 0; i < choices; i++ {
-		go Source[int64](RandomInt64(min, max), g.Sub("src.%d", i), Ls[i])
+		src := g.Sub("src.%d", i)
+		go Source[int64](RandomInt64(src.Rand(), min, max), src, Ls[i])
 	}
 	go Sink[int64](g.Sub("sink"), Rr)
 	go Merge[int64](g.Sub("dut"), Cr, Lr, Rs)
 }
 
+
+func TestIntegrationArbiter(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/arbiter")
+	inputs := param.Int(3, 3)
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	defer g.Done()
+
+	Ls, Lr := ChanArr[int]("L", inputs, 0)
+	Rs, Rr := Chan[int]("R", 0)
+	Cs, Cr := Chan[int]("C", 0)
+
+	for i := 0; i < inputs; i++ {
+		go SourceN[int](20, Values(i), g.Sub("src.%d", i), Ls[i])
+	}
+	go SinkAndCheckN[int](int64(20*inputs), AreEqual[int], g.Sub("sink"), Rr, Cr)
+	go Arbiter[int](RoundRobin(), g.Sub("dut"), Lr, Rs, Cs)
+}
+
+func TestIntegrationArbiterOrder(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/arbiter_order")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	g.SetDeterministic(true)
+
+	Ls, Lr := ChanArr[int]("L", 2, 0)
+	Rs, Rr := Chan[int]("R", 0)
+
+	// input 1 requests first, then both race at the same time
+	go func(g Globals) {
+		g.Init(Ls[0])
+		defer g.Done()
+		Ls[0].Send(0, 10)
+	}(g.Sub("src.0"))
+	go func(g Globals) {
+		g.Init(Ls[1])
+		defer g.Done()
+		Ls[1].Send(1, 5)
+		Ls[1].Send(1, 10-g.Curr())
+	}(g.Sub("src.1"))
+
+	var values []int
+	go func(g Globals) {
+		g.Init(Rr)
+		defer g.Done()
+		for {
+			x, _ := Rr.Recv()
+			values = append(values, x)
+		}
+	}(g.Sub("sink"))
+	go Arbiter[int](Priority, g.Sub("dut"), Lr, Rs, nil)
+	g.Done()
+
+	assert.Equal(t, []int{1, 0, 1}, values)
+}
+
+func TestIntegrationMutex(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/mutex")
+	clients := param.Int(3, 4)
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+
+	As, Ar := ChanArr[Void]("A", clients, 0)
+	Rs, Rr := ChanArr[Void]("R", clients, 0)
+
+	var mu sync.Mutex
+	inside := 0
+	grants := 0
+	for i := 0; i < clients; i++ {
+		go func(g Globals, A, R Sender[Void]) {
+			g.Init(A, R)
+			defer g.Done()
+			for j := 0; j < 10; j++ {
+				A.Send(Null)
+				mu.Lock()
+				inside++
+				grants++
+				assert.Equal(t, 1, inside)
+				mu.Unlock()
+
+				g.Timing()
+
+				mu.Lock()
+				inside--
+				mu.Unlock()
+				R.Send(Null)
+			}
+		}(g.Sub("client.%d", i), As[i], Rs[i])
+	}
+	go Mutex(FirstCome, g.Sub("dut"), Ar, Rr)
+	g.RandomTiming(100*time.Microsecond)
+	g.Done()
+
+	assert.Equal(t, 10*clients, grants)
+}
+
+func TestIntegrationFunc(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/func")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	defer g.Done()
+
+	Ls, Lr := Chan[int64]("L", 0)
+	Vs, Vr := Chan[int64]("V", 2)
+	Rs, Rr := Chan[int64]("R", 0)
+
+	square := func(token int64, values []int64) error {
+		if values[0] != values[1]*values[1] {
+			return fmt.Errorf("expected %d, found %d at token %d", values[1]*values[1], values[0], token)
+		}
+		return nil
+	}
+
+	src := g.Sub("src")
+	go SourceN(100, RandomInt64(src.Rand(), -1000, 1000), src, Ls, Vs)
+	go SinkAndCheck(square, g.Sub("sink"), Rr, Vr)
+	go Func(func(x int64) int64 { return x*x }, nil, g.Sub("dut"), Lr, Rs)
+}
+
+func TestIntegrationFunc2(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/func2")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	defer g.Done()
+
+	As, Ar := Chan[int64]("A", 0)
+	Bs, Br := Chan[int64]("B", 0)
+	Vs, Vr := Chan[int64]("V", 2)
+	Rs, Rr := Chan[int64]("R", 0)
+
+	double := func(token int64, values []int64) error {
+		if values[0] != 2*values[1] {
+			return fmt.Errorf("expected %d, found %d at token %d", 2*values[1], values[0], token)
+		}
+		return nil
+	}
+
+	src := g.Sub("src")
+	go SourceN(100, RandomInt64(src.Rand(), -1000, 1000), src, As, Bs, Vs)
+	go SinkAndCheck(double, g.Sub("sink"), Rr, Vr)
+	go Func2(func(a, b int64) int64 { return a+b }, nil, g.Sub("dut"), Ar, Br, Rs)
+}
+
+func TestIntegrationReduce(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/reduce")
+	inputs := param.Int(3, 5)
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	g.SetDeterministic(true)
+
+	Ls, Lr := ChanArr[int64]("L", inputs, 0)
+	Rs, Rr := Chan[int64]("R", 0)
+
+	for i := 0; i < inputs; i++ {
+		go SourceN(10, Values(int64(i)), g.Sub("src.%d", i), Ls[i])
+	}
+
+	var values []int64
+	var times []float64
+	go func(g Globals) {
+		g.Init(Rr)
+		defer g.Done()
+		for {
+			x, t := Rr.Recv()
+			values = append(values, x)
+			times = append(times, t)
+			g.Cycle(0, t, t)
+		}
+	}(g.Sub("sink"))
+
+	add := func(a, b int64) int64 { return a+b }
+	carry := func(a, b int64) (float64, float64) { return 1, 2 }
+	go Reduce(add, carry, g.Sub("dut"), Lr, Rs)
+	g.Done()
+
+	assert.Len(t, values, 10)
+	for _, x := range values {
+		assert.Equal(t, int64(inputs*(inputs-1)/2), x)
+	}
+	// three levels of adders on top of the profile
+	assert.InDelta(t, 3.1, times[0], 1e-9)
+}
+
+func TestUnitReduceTree(t *testing.T) {
+	add := func(a, b int) int { return a+b }
+	carry := func(a, b int) (float64, float64) { return 1, 2 }
+
+	x, d, e := reduceTree(add, carry, []int{1, 2, 3, 4, 5})
+	assert.Equal(t, 15, x)
+	assert.Equal(t, 3.0, d)
+	assert.Equal(t, 8.0, e)
+
+	x, d, e = reduceTree(add, nil, []int{7})
+	assert.Equal(t, 7, x)
+	assert.Equal(t, 0.0, d)
+	assert.Equal(t, 0.0, e)
+
+	// there is no tree without inputs
+	assert.PanicsWithValue(t, Misconfigured, func() {
+		Reduce[int](add, carry, nil, nil, nil)
+	})
+}
+
+func TestIntegrationMemory(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/memory")
+	size := param.Int(3, 8)
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+
+	RAs, RAr := Chan[int]("RA", 0)
+	Rs, Rr := Chan[int64]("R", 0)
+	WAs, WAr := Chan[int]("WA", 0)
+	Ws, Wr := Chan[int64]("W", 0)
+
+	var values []int64
+	go func(g Globals) {
+		g.Init(RAs, Rr, WAs, Ws)
+		defer g.Done()
+		for i := 0; i < size; i++ {
+			WAs.Send(i)
+			Ws.Send(int64(i*i))
+		}
+		for i := size-1; i >= 0; i-- {
+			RAs.Send(i)
+			x, _ := Rr.Recv()
+			values = append(values, x)
+		}
+	}(g.Sub("cpu"))
+
+	go Memory(make([]int64, size), g.Sub("mem"), RAr, Rs, WAr, Wr)
+	g.Done()
+
+	assert.Len(t, values, size)
+	for i, x := range values {
+		j := int64(size-1-i)
+		assert.Equal(t, j*j, x)
+	}
+}
+
+func TestIntegrationRegister(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/register")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+
+	Qs, Qr := Chan[Void]("Q", 0)
+	Rs, Rr := Chan[int]("R", 0)
+	Ws, Wr := Chan[int]("W", 0)
+
+	var values []int
+	go func(g Globals) {
+		g.Init(Qs, Rr, Ws)
+		defer g.Done()
+		for i := 0; i < 3; i++ {
+			Qs.Send(Void{})
+			x, _ := Rr.Recv()
+			values = append(values, x)
+			Ws.Send(i+10)
+		}
+	}(g.Sub("cpu"))
+
+	go Register(5, g.Sub("reg"), Qr, Rs, Wr)
+	g.Done()
+
+	assert.Equal(t, []int{5, 10, 11}, values)
+}
+
+func TestIntegrationAccumulator(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/accumulator")
+	loops := param.Int(3, 20)
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	defer g.Done()
+
+	Ns, Nr := Chan[int]("N", 0)
+	Is, Ir := Chan[int]("I", 0)
+	Cs, Cr := Chan[bool]("C", 0)
+	Rs, Rr := Chan[int]("R", 0)
+	Vs, Vr := Chan[int]("V", 2)
+
+	// the sum of 0 to n-1
+	sums := func(token int64, values []int) error {
+		n := values[1]
+		if values[0] != n*(n-1)/2 {
+			return fmt.Errorf("expected %d, found %d at token %d", n*(n-1)/2, values[0], token)
+		}
+		return nil
+	}
+
+	src := g.Sub("src")
+	go SourceN(int64(loops), RandomInt(src.Rand(), 1, 10), src, Ns, Vs)
+	go Counter(g.Sub("loop"), Nr, Is, Cs)
+	go Accumulator(func(a, b int) int { return a+b }, 0, g.Sub("acc"), Cr, Ir, Rs)
+	go SinkAndCheckN(int64(loops), sums, g.Sub("sink"), Rr, Vr)
+}
+
+func TestIntegrationFifo(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/fifo")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	g.SetDeterministic(true)
+
+	Ls, Lr := Chan[int]("L", 0)
+	Rs, Rr := Chan[int]("R", 0)
+
+	go SourceN(10, func(i int64) int { return int(i) }, g.Sub("src"), Ls)
+
+	var values []int
+	var times []float64
+	go func(g Globals) {
+		g.Init(Rr)
+		defer g.Done()
+		for {
+			x, t := Rr.Recv()
+			values = append(values, x)
+			times = append(times, g.Curr()+t)
+			g.Cycle(0, t, t)
+		}
+	}(g.Sub("sink"))
+
+	go Fifo(g.Sub("fifo"), Lr, Rs)
+	g.Done()
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, values)
+	// four stages forward the first token after 0.1 each
+	assert.InDelta(t, 0.4, times[0], 1e-9)
+}
+
+func TestIntegrationFilter(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/filter")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	defer g.Done()
+
+	Cs, Cr := Chan[bool]("C", 0)
+	Ls, Lr := Chan[int64]("L", 0)
+	Rs, Rr := Chan[int64]("R", 0)
+
+	// keep the multiples of 3 that are also even
+	multiples := func(token int64, values []int64) error {
+		if values[0] != 6*token {
+			return fmt.Errorf("expected %d, found %d at token %d", 6*token, values[0], token)
+		}
+		return nil
+	}
+
+	go SourceN(100, func(i int64) bool { return i%3 == 0 }, g.Sub("ctrl"), Cs)
+	go SourceN(100, func(i int64) int64 { return i }, g.Sub("src"), Ls)
+	go Filter(func(x int64) bool { return x%2 == 0 }, g.Sub("dut"), Cr, Lr, Rs)
+	go SinkAndCheck(multiples, g.Sub("sink"), Rr)
+}
+
+func TestIntegrationDrop(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/drop")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	defer g.Done()
+
+	Ls, Lr := Chan[int64]("L", 0)
+	Rs, Rr := Chan[int64]("R", 0)
+
+	skipped := func(token int64, values []int64) error {
+		if values[0] != token+5 {
+			return fmt.Errorf("expected %d, found %d at token %d", token+5, values[0], token)
+		}
+		return nil
+	}
+
+	go SourceN(20, func(i int64) int64 { return i }, g.Sub("src"), Ls)
+	go Drop[int64](5, g.Sub("dut"), Lr, Rs)
+	go SinkAndCheckN(15, skipped, g.Sub("sink"), Rr)
+}
+
+func TestIntegrationInitial(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/initial")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	defer g.Done()
+
+	As, Ar := Chan[int64]("A", 0)
+	Bs, Br := Chan[int64]("B", 0)
+	Cs, Cr := Chan[int64]("C", 0)
+	Os, Or := Chan[int64]("O", 0)
+
+	// a ring that counts up from the injected token
+	count := func(token int64, values []int64) error {
+		if values[0] != token {
+			return fmt.Errorf("expected %d, found %d at token %d", token, values[0], token)
+		}
+		return nil
+	}
+
+	go Inject[int64](0, g.Sub("init"), Br, As)
+	go Copy(g.Sub("copy"), Ar, []Sender[int64]{Cs, Os})
+	go Func(func(x int64) int64 { return x+1 }, nil, g.Sub("inc"), Cr, Bs)
+	go SinkAndCheckN(50, count, g.Sub("sink"), Or)
+}
+
+func TestIntegrationDemux(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/demux")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+
+	Ls, Lr := Chan[int]("L", 0)
+	Rs, Rr := ChanArr[int]("R", 3, 0)
+
+	values := make([][]int, 3)
+	src := g.Sub("src")
+	go SourceN(60, RandomInt(src.Rand(), 0, 100), src, Ls)
+	go Demux(func(x int) int { return x%3 }, g.Sub("dut"), Lr, Rs)
+	for i := range Rr {
+		go func(g Globals, i int) {
+			g.Init(Rr[i])
+			defer g.Done()
+			for {
+				x, t := Rr[i].Recv()
+				values[i] = append(values[i], x)
+				g.Cycle(0, t, t)
+			}
+		}(g.Sub("sink.%d", i), i)
+	}
+	g.Done()
+
+	total := 0
+	for i := range values {
+		for _, x := range values[i] {
+			assert.Equal(t, i, x%3)
+		}
+		total += len(values[i])
+	}
+	assert.Equal(t, 60, total)
+}
+
+func TestIntegrationCrossbar(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/crossbar")
+	inputs := param.Int(3, 3)
+	outputs := param.Int(4, 2)
+	count := 20
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	g.RandomTiming(100*time.Microsecond)
+
+	Ds, Dr := ChanArr[int]("D", inputs, 0)
+	Ls, Lr := ChanArr[int]("L", inputs, 0)
+	Rs, Rr := ChanArr[int]("R", outputs, 0)
+
+	// input i sends i*1000+k to output k%outputs
+	for i := 0; i < inputs; i++ {
+		i := i
+		go SourceN(int64(count), func(k int64) int { return int(k)%outputs }, g.Sub("dst.%d", i), Ds[i])
+		go SourceN(int64(count), func(k int64) int { return i*1000+int(k) }, g.Sub("src.%d", i), Ls[i])
+	}
+
+	values := make([][]int, outputs)
+	for j := 0; j < outputs; j++ {
+		go func(g Globals, j int) {
+			g.Init(Rr[j])
+			defer g.Done()
+			for {
+				x, t := Rr[j].Recv()
+				values[j] = append(values[j], x)
+				g.Cycle(0, t, t)
+			}
+		}(g.Sub("sink.%d", j), j)
+	}
+
+	go Crossbar(g.Sub("xbar"), Dr, Lr, Rs)
+	g.Done()
+
+	total := 0
+	for j := range values {
+		// tokens from each input stay in order
+		last := map[int]int{}
+		for _, x := range values[j] {
+			i, k := x/1000, x%1000
+			assert.Equal(t, j, k%outputs)
+			if prev, ok := last[i]; ok {
+				assert.Greater(t, k, prev)
+			}
+			last[i] = k
+		}
+		total += len(values[j])
+	}
+	assert.Equal(t, inputs*count, total)
+}
+
+func TestIntegrationZip(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/zip")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	defer g.Done()
+
+	Ls, Lr := ChanArr[int64]("L", 3, 0)
+	Zs, Zr := Chan[[]int64]("Z", 0)
+	Rs, Rr := ChanArr[int64]("R", 3, 0)
+	Vs, Vr := ChanArr[int64]("V", 3, 2)
+
+	for i := 0; i < 3; i++ {
+		src := g.Sub("src.%d", i)
+		go SourceN(100, RandomInt64(src.Rand(), -1000, 1000), src, Ls[i], Vs[i])
+		go SinkAndCheck(AreEqual[int64], g.Sub("sink.%d", i), Rr[i], Vr[i])
+	}
+	go Zip(g.Sub("zip"), Lr, Zs)
+	go Unzip(g.Sub("unzip"), Zr, Rs)
+}
+
+func TestIntegrationZipStruct(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/zipstruct")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	defer g.Done()
+
+	type pair struct {
+		A int
+		B bool
+	}
+
+	As, Ar := Chan[int]("A", 0)
+	Bs, Br := Chan[bool]("B", 0)
+	Ps, Pr := Chan[pair]("P", 0)
+	Xs, Xr := Chan[int]("X", 0)
+	Ys, Yr := Chan[bool]("Y", 0)
+	Vs, Vr := Chan[int]("V", 2)
+	Ws, Wr := Chan[bool]("W", 2)
+
+	srcA := g.Sub("srcA")
+	srcB := g.Sub("srcB")
+	go SourceN(100, RandomInt(srcA.Rand(), -1000, 1000), srcA, As, Vs)
+	go SourceN(100, RandomBool(srcB.Rand()), srcB, Bs, Ws)
+	go ZipStruct[pair](g.Sub("zip"), []interface{}{Ar, Br}, Ps)
+	go UnzipStruct(g.Sub("unzip"), Pr, []interface{}{Xs, Ys})
+	go SinkAndCheck(AreEqual[int], g.Sub("sinkX"), Xr, Vr)
+	go SinkAndCheck(AreEqual[bool], g.Sub("sinkY"), Yr, Wr)
+}
+
+func TestUnitFields(t *testing.T) {
+	type pair struct {
+		A int
+		B bool
+	}
+
+	_, Ar := Chan[int]("A", 0)
+	_, Br := Chan[bool]("B", 0)
+	assert.Len(t, fields[pair]([]interface{}{Ar, Br}, "Recv"), 2)
+	assert.Panics(t, func() { fields[pair]([]interface{}{Br, Ar}, "Recv") })
+	assert.Panics(t, func() { fields[pair]([]interface{}{Ar}, "Recv") })
+	assert.Panics(t, func() { fields[int]([]interface{}{Ar}, "Recv") })
+}
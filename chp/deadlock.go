@@ -0,0 +1,498 @@
+package chp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// how often the watchdog checks for a deadlock and how many checks in a row
+// must find every process blocked without any progress
+const (
+	watchdogPeriod = 50 * time.Millisecond
+	watchdogChecks = 4
+)
+
+const (
+	running = iota
+	// waiting in Done for its children
+	stopping
+	// returned normally
+	finished
+	// panicked with timing.Deadlock
+	deadlocked
+)
+
+// chanState is a snapshot of a channel at the time of the deadlock
+type chanState struct {
+	full       bool
+	empty      bool
+	sendClosed bool
+	recvClosed bool
+}
+
+func (s chanState) String() string {
+	var result []string
+	if s.full {
+		result = append(result, "full")
+	} else if s.empty {
+		result = append(result, "empty")
+	}
+	if s.sendClosed {
+		result = append(result, "sender closed")
+	}
+	if s.recvClosed {
+		result = append(result, "receiver closed")
+	}
+	return strings.Join(result, ", ")
+}
+
+// watched is implemented by every channel so that the monitor can inspect
+// and abort it without knowing its type
+type watched interface {
+	chanName() string
+	typeName() string
+	endpoints() (*globals, *globals)
+	snapshot() chanState
+	abort()
+}
+
+// pending is a channel operation that a process is waiting on
+type pending struct {
+	c     watched
+	send  bool
+	op    string
+	state chanState
+	// the operation failed with a deadlock
+	dead bool
+}
+
+// monitor tracks every process and channel of a simulation in order to
+// detect and explain deadlocks
+type monitor struct {
+	mu        sync.Mutex
+	progress  uint64
+	processes []*globals
+	channels  []watched
+	// set when the watchdog found every process blocked and aborted the
+	// simulation
+	aborted bool
+	// set once the network was checked for dangling channels
+	checked bool
+}
+
+func (m *monitor) register(g *globals) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.processes = append(m.processes, g)
+}
+
+func (m *monitor) watch(c watched) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channels = append(m.channels, c)
+}
+
+func (m *monitor) setStatus(g *globals, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g.status = status
+}
+
+// beginOp records that the process is about to wait on a channel. Operations
+// that end in a deadlock never reach endOp so that the report can show them.
+func beginOp(g Globals, c watched, send bool, op string) *pending {
+	gl, ok := g.(*globals)
+	if !ok || gl.monitor == nil {
+		return nil
+	}
+	p := &pending{
+		c:    c,
+		send: send,
+		op:   op,
+	}
+	gl.monitor.mu.Lock()
+	defer gl.monitor.mu.Unlock()
+	gl.pending = append(gl.pending, p)
+	return p
+}
+
+// failOp records the state of the channel when an operation fails with a
+// deadlock. An operation that failed because its own process closed the
+// channel wasn't waiting on anyone and is dropped.
+func failOp(g Globals, p *pending) {
+	gl, ok := g.(*globals)
+	if !ok || p == nil {
+		return
+	}
+	gl.monitor.mu.Lock()
+	defer gl.monitor.mu.Unlock()
+	if !gl.monitor.aborted {
+		p.state = p.c.snapshot()
+		if (p.send && p.state.sendClosed) || (!p.send && p.state.recvClosed) {
+			for i, q := range gl.pending {
+				if q == p {
+					gl.pending = append(gl.pending[0:i], gl.pending[i+1:]...)
+					break
+				}
+			}
+			return
+		}
+	}
+	p.dead = true
+}
+
+func endOp(g Globals, p *pending) {
+	gl, ok := g.(*globals)
+	if !ok || p == nil {
+		return
+	}
+	gl.monitor.mu.Lock()
+	defer gl.monitor.mu.Unlock()
+	for i, q := range gl.pending {
+		if q == p {
+			gl.pending = append(gl.pending[0:i], gl.pending[i+1:]...)
+			break
+		}
+	}
+	gl.monitor.progress++
+}
+
+// stalled checks whether every running process is waiting on a channel
+func (m *monitor) stalled() (bool, uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	live := 0
+	for _, g := range m.processes {
+		if g.status == running {
+			if len(g.pending) == 0 {
+				return false, m.progress
+			}
+			live++
+		}
+	}
+	return live > 0, m.progress
+}
+
+// abort wakes every blocked process with a deadlock after recording what
+// each was waiting on
+func (m *monitor) abort() {
+	m.mu.Lock()
+	m.aborted = true
+	for _, g := range m.processes {
+		for _, p := range g.pending {
+			p.state = p.c.snapshot()
+		}
+	}
+	channels := append([]watched{}, m.channels...)
+	m.mu.Unlock()
+
+	for _, c := range channels {
+		c.abort()
+	}
+}
+
+// settle waits until every running process other than g and the processes
+// above it has called Init, or until none has for a while since a process
+// may never call it
+func (m *monitor) settle(g *globals) {
+	ticker := time.NewTicker(watchdogPeriod)
+	defer ticker.Stop()
+
+	count := 0
+	last := -1
+	for {
+		waiting := m.uninitialized(g)
+		if waiting == 0 {
+			return
+		} else if waiting != last {
+			count = 0
+			last = waiting
+		} else if count++; count >= watchdogChecks {
+			return
+		}
+		<-ticker.C
+	}
+}
+
+// uninitialized counts the running processes that haven't called Init yet,
+// ignoring g and the processes above it
+func (m *monitor) uninitialized(g *globals) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	above := map[*globals]bool{}
+	for p := g; p != nil; p = p.parent {
+		above[p] = true
+	}
+	count := 0
+	for _, p := range m.processes {
+		if !above[p] && !p.init && p.status == running {
+			count++
+		}
+	}
+	return count
+}
+
+// watchdog detects deadlocks that would otherwise hang the simulation:
+// every process is waiting on a channel and nothing has happened for a
+// while. A deadlock caused by a process that finished is handled by the
+// channels themselves.
+func (m *monitor) watchdog(stop chan struct{}, maxDelay time.Duration) {
+	checks := watchdogChecks
+	if n := int(2*maxDelay/watchdogPeriod) + 1; n > checks {
+		checks = n
+	}
+
+	ticker := time.NewTicker(watchdogPeriod)
+	defer ticker.Stop()
+
+	count := 0
+	var last uint64
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		stalled, progress := m.stalled()
+		if !stalled || progress != last {
+			count = 0
+			last = progress
+			continue
+		}
+
+		count++
+		if count >= checks {
+			m.abort()
+			return
+		}
+	}
+}
+
+// edge of the wait-for graph, from waits on to
+type edge struct {
+	from *globals
+	to   *globals
+	p    *pending
+}
+
+func (e edge) String() string {
+	dir := "?"
+	if e.p.send {
+		dir = "!"
+	}
+	to := "nobody"
+	if e.to != nil {
+		to = e.to.name
+	}
+	return fmt.Sprintf("%s -> %s (%s%s)", e.from.name, to, e.p.c.chanName(), dir)
+}
+
+// graph builds the wait-for graph from the operations that failed with a
+// deadlock
+func (m *monitor) graph() ([]*globals, map[*globals][]edge) {
+	var blocked []*globals
+	edges := map[*globals][]edge{}
+	for _, g := range m.processes {
+		for _, p := range g.pending {
+			if !p.dead {
+				continue
+			}
+			if len(edges[g]) == 0 {
+				blocked = append(blocked, g)
+			}
+			s, r := p.c.endpoints()
+			to := s
+			if p.send {
+				to = r
+			}
+			edges[g] = append(edges[g], edge{g, to, p})
+		}
+	}
+	sort.Slice(blocked, func(i, j int) bool {
+		return blocked[i].name < blocked[j].name
+	})
+	return blocked, edges
+}
+
+// cycles finds the strongly connected components of the wait-for graph
+// that contain a cycle
+func cycles(blocked []*globals, edges map[*globals][]edge) [][]*globals {
+	index := map[*globals]int{}
+	low := map[*globals]int{}
+	onStack := map[*globals]bool{}
+	var stack []*globals
+	var result [][]*globals
+
+	var visit func(g *globals)
+	visit = func(g *globals) {
+		index[g] = len(index)
+		low[g] = index[g]
+		stack = append(stack, g)
+		onStack[g] = true
+
+		self := false
+		for _, e := range edges[g] {
+			if e.to == nil {
+				continue
+			} else if e.to == g {
+				self = true
+			}
+			if _, ok := index[e.to]; !ok {
+				visit(e.to)
+				if low[e.to] < low[g] {
+					low[g] = low[e.to]
+				}
+			} else if onStack[e.to] && index[e.to] < low[g] {
+				low[g] = index[e.to]
+			}
+		}
+
+		if low[g] == index[g] {
+			var scc []*globals
+			for {
+				n := stack[len(stack)-1]
+				stack = stack[0 : len(stack)-1]
+				onStack[n] = false
+				scc = append(scc, n)
+				if n == g {
+					break
+				}
+			}
+			if len(scc) > 1 || self {
+				sort.Slice(scc, func(i, j int) bool {
+					return scc[i].name < scc[j].name
+				})
+				result = append(result, scc)
+			}
+		}
+	}
+
+	for _, g := range blocked {
+		if _, ok := index[g]; !ok {
+			visit(g)
+		}
+	}
+	return result
+}
+
+// starved groups the blocked processes by the finished processes they were
+// ultimately waiting on
+func starved(blocked []*globals, edges map[*globals][]edge) (map[*globals][]*globals, []*globals) {
+	result := map[*globals][]*globals{}
+	var sources []*globals
+	for _, g := range blocked {
+		seen := map[*globals]bool{g: true}
+		queue := []*globals{g}
+		for len(queue) > 0 {
+			n := queue[0]
+			queue = queue[1:]
+			for _, e := range edges[n] {
+				if e.to == nil || seen[e.to] {
+					continue
+				}
+				seen[e.to] = true
+				if e.to.status == finished {
+					if _, ok := result[e.to]; !ok {
+						sources = append(sources, e.to)
+					}
+					result[e.to] = append(result[e.to], g)
+				} else {
+					queue = append(queue, e.to)
+				}
+			}
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].name < sources[j].name
+	})
+	return result, sources
+}
+
+func names(procs []*globals) string {
+	result := make([]string, len(procs))
+	for i, g := range procs {
+		result[i] = g.name
+	}
+	return strings.Join(result, ", ")
+}
+
+// report writes the wait-for graph and a diagnosis of the deadlock. It
+// returns false if no process deadlocked.
+func (m *monitor) report(w io.Writer) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	blocked, edges := m.graph()
+	if len(blocked) == 0 {
+		return false
+	}
+
+	fmt.Fprintf(w, "blocked processes\n")
+	for _, g := range blocked {
+		for _, e := range edges[g] {
+			peer := "no process"
+			if e.to != nil {
+				peer = e.to.name
+			}
+			fmt.Fprintf(w, "\t%s\t%s %s\twith %s\t%s\n", g.name, e.p.op, e.p.c.chanName(), peer, e.p.state)
+		}
+	}
+
+	fmt.Fprintf(w, "\nwait-for graph\n")
+	for _, g := range blocked {
+		for _, e := range edges[g] {
+			fmt.Fprintf(w, "\t%s\n", e)
+		}
+	}
+
+	fmt.Fprintf(w, "\ndiagnosis\n")
+	loops := cycles(blocked, edges)
+	for _, scc := range loops {
+		fmt.Fprintf(w, "\tprotocol deadlock, cycle between %s\n", names(scc))
+	}
+
+	groups, sources := starved(blocked, edges)
+	for _, src := range sources {
+		fmt.Fprintf(w, "\tran out of tokens, %s finished while %s waited on it\n", src.name, names(groups[src]))
+	}
+
+	dangling := 0
+	for _, g := range blocked {
+		for _, e := range edges[g] {
+			if e.to == nil {
+				fmt.Fprintf(w, "\tdangling channel, %s has no process on the other side\n", e.p.c.chanName())
+				dangling++
+			}
+		}
+	}
+
+	if len(loops) == 0 && len(sources) == 0 && dangling == 0 && m.aborted {
+		fmt.Fprintf(w, "\tprotocol deadlock, every process is blocked\n")
+	}
+	return true
+}
+
+// diagnose writes the deadlock report into the run directory, printing it
+// as well if the deadlock wasn't just the simulation running out of tokens
+func (m *monitor) diagnose(dir string, debug bool) {
+	var buf strings.Builder
+	if !m.report(&buf) {
+		return
+	}
+
+	err := os.WriteFile(filepath.Join(dir, "deadlock"), []byte(buf.String()), 0644)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	if debug || m.aborted || strings.Contains(buf.String(), "protocol deadlock") {
+		fmt.Print(buf.String())
+	}
+}
@@ -0,0 +1,65 @@
+package chp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/param"
+)
+
+func TestIntegrationDeadlockCycle(t *testing.T) {
+	out := param.String(2, "test/chp/deadlock_cycle")
+
+	g, err := New(out)
+	assert.NoError(t, err)
+
+	As, Ar := Chan[int64]("A", 0)
+	Bs, Br := Chan[int64]("B", 0)
+
+	// both processes wait to receive before sending
+	go func(g Globals) {
+		g.Init(Ar, Bs)
+		defer g.Done()
+		Ar.Recv()
+		Bs.Send(0)
+	}(g.Sub("left"))
+	go func(g Globals) {
+		g.Init(Br, As)
+		defer g.Done()
+		Br.Recv()
+		As.Send(0)
+	}(g.Sub("right"))
+	g.Done()
+
+	report, err := os.ReadFile(filepath.Join(out, "deadlock"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(report), "top.left -> top.right (A?)")
+	assert.Contains(t, string(report), "top.right -> top.left (B?)")
+	assert.Contains(t, string(report), "protocol deadlock, cycle between top.left, top.right")
+	assert.NotContains(t, string(report), "ran out of tokens")
+}
+
+func TestIntegrationDeadlockTokens(t *testing.T) {
+	out := param.String(2, "test/chp/deadlock_tokens")
+
+	g, err := New(out)
+	assert.NoError(t, err)
+
+	Ls, Lr := Chan[int64]("L", 0)
+	Rs, Rr := Chan[int64]("R", 0)
+
+	src := g.Sub("src")
+	go SourceN(10, RandomInt64(src.Rand(), 0, 2), src, Ls)
+	go Buffer(g.Sub("buf"), Lr, Rs)
+	go Sink(g.Sub("sink"), Rr)
+	g.Done()
+
+	report, err := os.ReadFile(filepath.Join(out, "deadlock"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(report), "top.buf -> top.src (L?)")
+	assert.Contains(t, string(report), "top.sink -> top.buf (R?)")
+	assert.Contains(t, string(report), "ran out of tokens, top.src finished while top.buf, top.sink waited on it")
+	assert.NotContains(t, string(report), "protocol deadlock")
+}
@@ -11,9 +11,13 @@ import (
 	"path/filepath"
 	"runtime"
 	"math/rand"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"git.broccolimicro.io/Broccoli/pr.git/chp/timing"
+	"git.broccolimicro.io/Broccoli/pr.git/chp/trace"
+	"git.broccolimicro.io/Broccoli/pr.git/chp/vcd"
 )
 
 var Misconfigured = errors.New("Misconfigured")
@@ -33,6 +37,46 @@ type Globals interface {
 	
 	RandomTiming(maxDelay time.Duration)
 	Timing()
+
+	// warn about every key read from a profile that falls back to 0.0, in
+	// this process, the processes created from it so far and those created
+	// with Sub afterwards
+	SetProfileWarnings(enable bool)
+
+	// the seed of this run, recorded in <dir>/seed if a process drew a
+	// random number from it
+	Seed() int64
+	// replay a previous run from its seed, must be called on the top level
+	// process before Sub
+	SetSeed(seed int64)
+	// random numbers for this process, derived from the seed and the
+	// process name so that a run can be replayed
+	Rand() *rand.Rand
+
+	// record a structured trace of every process in <dir>, either
+	// trace.JSON or trace.Binary, or stop recording it with "". Must be
+	// called on the top level process before Sub.
+	SetTrace(format string)
+	// structured trace shared by every process, nil if disabled
+	Trace() trace.Writer
+
+	// record channel handshakes in <dir>/<top>.vcd, nil if disabled
+	SetVCD(enable bool)
+	VCD() *vcd.Writer
+
+	// run one process at a time in order of simulated time so that every
+	// run produces the same trace, must be called on the top level process
+	// before Sub
+	SetDeterministic(enable bool)
+
+	// the processes and channels of the whole simulation built so far
+	Netlist() *Netlist
+
+	// wait until every process created so far has called Init and describe
+	// the channels missing a side, which would otherwise only show up as a
+	// deadlock. Done on the top level process warns about them at the end
+	// of the run if Check wasn't called.
+	Check() error
 }
 
 type cycle struct {
@@ -53,14 +97,49 @@ type globals struct {
 	// timing profile
 	t timing.ProfileSet
 	maxDelay time.Duration
+	profileWarnings bool
+	// the entry of the profile set this process matched, how it matched
+	// and the keys it read, recorded in <dir>/profile-usage
+	profile timing.Profile
+	profileKey string
+	profileBy string
+	// guards reads and profileWarnings, separate from the monitor so that
+	// reading a profile doesn't contend with every other process
+	readsMu sync.Mutex
+	reads map[string]bool
+
+	seed int64
+	rand *rand.Rand
+	// set once any process draws a random number, shared by every process
+	drawn *atomic.Bool
 
 	// cycle logger
 	log *os.File
 	dir string
+	trace trace.Writer
+
+	// waveform, only the top level process owns the file
+	vcd *vcd.Writer
+	vcdFile *vcd.Writer
 
 	debug bool
 
 	init bool
+
+	// deadlock detection, shared by every process
+	monitor *monitor
+	// the channel operations this process is waiting on
+	pending []*pending
+	status int
+
+	// deterministic scheduler, nil if disabled
+	kernel *kernel
+	task *task
+
+	// the block this process instantiates and the channels bound to its
+	// ports, nil if it isn't one
+	block *Block
+	bindings []interface{}
 }
 
 func New(args ...string) (Globals, error) {
@@ -91,19 +170,34 @@ func New(args ...string) (Globals, error) {
 		return nil, err
 	}
 
-	return &globals{
+	// a new seed unless SetSeed replays a previous run
+	seed := time.Now().UnixNano()
+	drawn := &atomic.Bool{}
+
+	g := &globals{
 		name: name,
 		dir: dir,
 		wg: &sync.WaitGroup{},
 		t: t,
-	}, nil
+		seed: seed,
+		rand: newRand(seed, name, drawn),
+		drawn: drawn,
+		monitor: &monitor{},
+	}
+	g.monitor.register(g)
+	return g, nil
 }
 
 func (g *globals) Sub(name string, args ...any) Globals {
+	g.enter()
 	g.wg.Add(1)
 
 	name = g.name + "." + fmt.Sprintf(name, args...)
 
+	g.readsMu.Lock()
+	profileWarnings := g.profileWarnings
+	g.readsMu.Unlock()
+
 	child := &globals {
 		name: name,
 		dir: g.dir,
@@ -111,8 +205,20 @@ func (g *globals) Sub(name string, args ...any) Globals {
 		wg: &sync.WaitGroup{},
 		debug: g.debug,
 		t: g.t,
+		profileWarnings: profileWarnings,
+		seed: g.seed,
+		rand: newRand(g.seed, name, g.drawn),
+		drawn: g.drawn,
+		trace: g.trace,
+		vcd: g.vcd,
+		monitor: g.monitor,
+		kernel: g.kernel,
+	}
+	if g.kernel != nil {
+		child.task = g.kernel.add(g.task, g.curr)
 	}
 	g.children = append(g.children, child)
+	g.monitor.register(child)
 	return child
 }
 
@@ -125,13 +231,23 @@ func caller(skipFrames int) string {
 	return ""
 }
 
+// enter waits for the kernel to schedule the goroutine running this process
+func (g *globals) enter() {
+	if g.kernel != nil && !g.task.started {
+		g.kernel.start(g.task)
+	}
+}
+
 func (g *globals) Init(args ...interface{}) timing.Profile {
+	g.enter()
 	if g.init {
 		panic(Misconfigured)
 	}
-	g.init = true
 
+	g.monitor.mu.Lock()
+	g.init = true
 	g.ports = args
+	g.monitor.mu.Unlock()
 	for _, port := range g.ports {
 		if port != nil {
 			if c, ok := port.(Recordable); ok {
@@ -148,28 +264,69 @@ func (g *globals) Init(args ...interface{}) timing.Profile {
 		}
 	}
 
+	var p timing.Profile
 	if g.t != nil {
-		p := g.t.Find(g.name)
-		if p != nil {
-			return p
-		}
-
-		p = g.t.Find(caller(0))
-		if p != nil {
-			return p
-		}
+		var i int
+		p, g.profileKey, i = g.t.Match(g.name, caller(0))
+		g.profileBy = []string{"by default", "by name", "by function"}[i+1]
+	}
+	if p == nil {
+		p = timing.NewProfile()
 	}
+	g.profile = p
+	return timing.Watch(timing.WithRand(p, g.rand), g.readProfile)
+}
 
-	return timing.NewProfile()
+// readProfile records a key this process reads from its profile and
+// whether it was there, warning about missing keys if enabled
+func (g *globals) readProfile(name string, ok bool) {
+	g.readsMu.Lock()
+	defer g.readsMu.Unlock()
+	if _, seen := g.reads[name]; seen {
+		return
+	}
+	if g.reads == nil {
+		g.reads = make(map[string]bool)
+	}
+	g.reads[name] = ok
+	if !ok && g.profileWarnings {
+		fmt.Printf("warning: %s has no %s in its profile, using 0.0\n", g.name, name)
+	}
 }
 
 func (g *globals) Done() {
-	if len(g.children) > 0 {
+	g.enter()
+	g.monitor.setStatus(g, stopping)
+	if g.kernel != nil {
+		// the kernel finds deadlocks as soon as every process is blocked.
+		// The top level process also waits for any goroutines left behind
+		// by Offer, Expect, Watch or Read.
+		if g.parent == nil {
+			g.kernel.drain()
+		} else {
+			g.kernel.join()
+		}
 		g.wg.Wait()
+	} else if len(g.children) > 0 {
+		if g.parent == nil {
+			// a deadlock between processes that are all still running would
+			// otherwise hang here forever
+			stop := make(chan struct{})
+			go g.monitor.watchdog(stop, g.maxDelay)
+			g.wg.Wait()
+			close(stop)
+		} else {
+			g.wg.Wait()
+		}
 	}
 
-	if r := recover(); r != nil && r != timing.Deadlock {
+	r := recover()
+	if r != nil && r != timing.Deadlock {
 		panic(r)
+	} else if r == timing.Deadlock {
+		g.monitor.setStatus(g, deadlocked)
+	} else {
+		g.monitor.setStatus(g, finished)
 	}
 	for _, port := range g.ports {
 		if port != nil {
@@ -203,6 +360,30 @@ func (g *globals) Done() {
 
 	if g.parent != nil {
 		g.parent.wg.Done()
+	} else {
+		if g.trace != nil {
+			err := g.trace.Close()
+			if err != nil {
+				fmt.Println(err)
+			}
+		}
+		if g.vcdFile != nil {
+			err := g.vcdFile.Close()
+			if err != nil {
+				fmt.Println(err)
+			}
+		}
+		// every process has called Init or never will by now, so there is
+		// no need to wait for the netlist to settle
+		g.warnDangling()
+		g.writeSeed()
+		g.writeNetlist()
+		g.writeProfileUsage()
+		g.monitor.diagnose(g.dir, g.debug)
+	}
+
+	if g.kernel != nil {
+		g.kernel.exit()
 	}
 }
 
@@ -219,6 +400,18 @@ func (g *globals) Cycle(fJ, start, end float64) {
 	if g.log != nil {
 		fmt.Fprintf(g.log, "%f\t%f\t%f\n", g.curr+start, g.curr+end, fJ)
 	}
+	if g.trace != nil {
+		err := g.trace.Write(trace.Event{
+			Process: g.name,
+			Dir: trace.Cycle,
+			Start: g.curr+start,
+			End: g.curr+end,
+			Energy: fJ,
+		})
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
 	g.curr += end
 }
 
@@ -234,6 +427,40 @@ func (g *globals) Curr() float64 {
 	return g.curr
 }
 
+func (g *globals) SetTrace(format string) {
+	if g.parent != nil || len(g.children) > 0 {
+		panic(Misconfigured)
+	}
+	if g.trace != nil {
+		if err := g.trace.Close(); err != nil {
+			fmt.Println(err)
+		}
+		g.trace = nil
+	}
+	if format == "" {
+		return
+	}
+
+	var err error
+	g.trace, err = trace.Create(g.dir, format)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (g *globals) Trace() trace.Writer {
+	return g.trace
+}
+
+func (g *globals) SetProfileWarnings(enable bool) {
+	g.readsMu.Lock()
+	g.profileWarnings = enable
+	g.readsMu.Unlock()
+	for _, child := range g.children {
+		child.SetProfileWarnings(enable)
+	}
+}
+
 func (g *globals) SetDebug(debug bool) {
 	g.debug = debug
 	for _, child := range g.children {
@@ -245,6 +472,126 @@ func (g *globals) Debug() bool {
 	return g.debug
 }
 
+func (g *globals) SetVCD(enable bool) {
+	var w *vcd.Writer
+	if enable {
+		root := g
+		for root.parent != nil {
+			root = root.parent
+		}
+		if root.vcdFile == nil {
+			var err error
+			root.vcdFile, err = vcd.Create(filepath.Join(root.dir, root.name+".vcd"))
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+		w = root.vcdFile
+	}
+	g.setVCD(w)
+}
+
+func (g *globals) setVCD(w *vcd.Writer) {
+	g.vcd = w
+	for _, child := range g.children {
+		child.setVCD(w)
+	}
+}
+
+func (g *globals) VCD() *vcd.Writer {
+	return g.vcd
+}
+
+func (g *globals) SetDeterministic(enable bool) {
+	if g.parent != nil || len(g.children) > 0 {
+		panic(Misconfigured)
+	}
+	if !enable {
+		if g.kernel != nil {
+			stopKernel(g.kernel)
+		}
+		g.kernel = nil
+		g.task = nil
+	} else if g.kernel == nil {
+		g.kernel, g.task = newKernel(g.monitor, g.curr)
+	}
+}
+
+func (g *globals) Netlist() *Netlist {
+	return g.monitor.netlist()
+}
+
+// writeProfileUsage records which profiles were used into
+// <dir>/profile-usage
+func (g *globals) writeProfileUsage() {
+	var buf strings.Builder
+	g.monitor.profileUsage(&buf, g.t)
+	err := os.WriteFile(filepath.Join(g.dir, "profile-usage"), []byte(buf.String()), 0644)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (g *globals) Check() error {
+	g.enter()
+	if g.kernel != nil {
+		g.kernel.settle()
+	} else {
+		g.monitor.settle(g)
+	}
+	g.monitor.mu.Lock()
+	g.monitor.checked = true
+	g.monitor.mu.Unlock()
+	return g.dangling()
+}
+
+// warnDangling prints the dangling channels unless Check was called
+func (g *globals) warnDangling() {
+	g.monitor.mu.Lock()
+	checked := g.monitor.checked
+	g.monitor.mu.Unlock()
+	if err := g.dangling(); err != nil && !checked {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Printf("warning: %s\n", line)
+		}
+	}
+}
+
+func (g *globals) dangling() error {
+	var lines []string
+	for _, c := range g.Netlist().Dangling() {
+		if c.Sender == "" {
+			lines = append(lines, fmt.Sprintf("dangling channel %s, nobody sends to %s", c.Name, c.Receiver))
+		} else {
+			lines = append(lines, fmt.Sprintf("dangling channel %s, nobody receives from %s", c.Name, c.Sender))
+		}
+	}
+	if len(lines) > 0 {
+		return errors.New(strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+// writeNetlist exports the network into the run directory
+func (g *globals) writeNetlist() {
+	n := g.Netlist()
+	var dot, js strings.Builder
+	err := n.WriteDOT(&dot)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(g.dir, "netlist.dot"), []byte(dot.String()), 0644)
+	}
+	if err == nil {
+		err = n.WriteJSON(&js)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(g.dir, "netlist.json"), []byte(js.String()), 0644)
+	}
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
 func (g *globals) RandomTiming(maxDelay time.Duration) {
 	g.maxDelay = maxDelay
 	for _, child := range g.children {
@@ -253,8 +600,37 @@ func (g *globals) RandomTiming(maxDelay time.Duration) {
 }
 
 func (g *globals) Timing() {
-	if g.maxDelay > 0 {
-		time.Sleep(time.Duration(rand.Int63n(int64(g.maxDelay))))
+	// random delays only shake out races between goroutines, the kernel
+	// has none
+	if g.maxDelay > 0 && g.kernel == nil {
+		time.Sleep(time.Duration(g.rand.Int63n(int64(g.maxDelay))))
+	}
+}
+
+func (g *globals) Seed() int64 {
+	return g.seed
+}
+
+func (g *globals) SetSeed(seed int64) {
+	if g.parent != nil || len(g.children) > 0 {
+		panic(Misconfigured)
+	}
+	g.seed = seed
+	g.rand = newRand(seed, g.name, g.drawn)
+}
+
+func (g *globals) Rand() *rand.Rand {
+	return g.rand
+}
+
+// writeSeed records the seed in the run directory if the run depended on it
+func (g *globals) writeSeed() {
+	if !g.drawn.Load() {
+		return
+	}
+	err := os.WriteFile(filepath.Join(g.dir, "seed"), []byte(fmt.Sprintf("%d\n", g.seed)), 0644)
+	if err != nil {
+		fmt.Println(err)
 	}
 }
 
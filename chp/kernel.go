@@ -0,0 +1,376 @@
+package chp
+
+import (
+	"sync"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/timing"
+)
+
+const (
+	ready = iota
+	active
+	// waiting for another process to change a channel
+	parked
+	// waiting for the result of an Offer, Expect, Watch or Read
+	awaiting
+	// waiting in Done for its children
+	joining
+	// waiting in Done for every other task
+	draining
+	// waiting in Check for every other task to start
+	settling
+	exited
+)
+
+// task is a goroutine scheduled by the kernel, either the body of a process
+// or one of the goroutines behind a non-blocking channel action
+type task struct {
+	k   *kernel
+	seq int
+	// the simulated time of the next channel event
+	time    float64
+	state   int
+	started bool
+
+	parent   *task
+	children int
+
+	// the channels this task is parked on
+	channels []watched
+	// the task waiting on this one to finish
+	awaiter *task
+	promise interface{}
+}
+
+// kernel runs one task at a time, always choosing the ready task with the
+// earliest simulated time and breaking ties by the order in which the tasks
+// were created. Given the same processes the same events happen in the same
+// order on every run.
+type kernel struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	current *task
+	tasks   []*task
+	seq     int
+
+	monitor *monitor
+}
+
+// promises maps the channel behind each pending Action or Signal to the task
+// that will fill it
+var promises sync.Map
+
+// kernels holds the kernel of each deterministic simulation in progress.
+// OnAction and OnSignal aren't given a process and join the simulation
+// through it.
+var kernels = struct {
+	sync.Mutex
+	live map[*kernel]bool
+}{live: map[*kernel]bool{}}
+
+// runningKernel returns the kernel of the deterministic simulation in
+// progress, nil if there is none or more than one
+func runningKernel() *kernel {
+	kernels.Lock()
+	defer kernels.Unlock()
+	if len(kernels.live) != 1 {
+		return nil
+	}
+	for k := range kernels.live {
+		return k
+	}
+	return nil
+}
+
+func stopKernel(k *kernel) {
+	kernels.Lock()
+	defer kernels.Unlock()
+	delete(kernels.live, k)
+}
+
+func init() {
+	timing.Wait = awaitPromise
+}
+
+// newKernel returns the kernel along with the task of the top level
+// process, which is already running
+func newKernel(m *monitor, time float64) (*kernel, *task) {
+	k := &kernel{
+		monitor: m,
+	}
+	k.cond = sync.NewCond(&k.mu)
+	t := k.add(nil, time)
+	t.state = active
+	t.started = true
+	k.current = t
+
+	kernels.Lock()
+	kernels.live[k] = true
+	kernels.Unlock()
+	return k, t
+}
+
+func (k *kernel) add(parent *task, time float64) *task {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	t := &task{
+		k:      k,
+		seq:    k.seq,
+		time:   time,
+		state:  ready,
+		parent: parent,
+	}
+	k.seq++
+	if parent != nil {
+		parent.children++
+	}
+	k.tasks = append(k.tasks, t)
+	return t
+}
+
+// schedule hands control to the earliest ready task, must be called with
+// k.mu held. If every remaining task is parked on a channel, the processes
+// have deadlocked and the channels are aborted to wake them up.
+func (k *kernel) schedule() {
+	if k.started() {
+		for _, t := range k.tasks {
+			if t.state == settling {
+				t.state = ready
+			}
+		}
+	}
+
+	next := k.earliest()
+	if next == nil && k.deadlocked() {
+		k.mu.Unlock()
+		k.monitor.abort()
+		k.mu.Lock()
+		next = k.earliest()
+	}
+
+	k.current = next
+	if next != nil {
+		next.state = active
+	}
+	k.cond.Broadcast()
+}
+
+func (k *kernel) earliest() *task {
+	var next *task
+	for _, t := range k.tasks {
+		if t.state == ready && (next == nil || t.time < next.time || (t.time == next.time && t.seq < next.seq)) {
+			next = t
+		}
+	}
+	return next
+}
+
+// started checks whether every task other than the settling ones has run,
+// must be called with k.mu held
+func (k *kernel) started() bool {
+	for _, t := range k.tasks {
+		if t.state != settling && !t.started {
+			return false
+		}
+	}
+	return true
+}
+
+func (k *kernel) deadlocked() bool {
+	for _, t := range k.tasks {
+		if t.state == parked {
+			return true
+		}
+	}
+	return false
+}
+
+// switchTo blocks the current task until it is scheduled again, must be
+// called with k.mu held
+func (k *kernel) switchTo(t *task) {
+	k.schedule()
+	for k.current != t {
+		k.cond.Wait()
+	}
+}
+
+// start blocks a newly created goroutine until its task is scheduled
+func (k *kernel) start(t *task) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for k.current != t {
+		k.cond.Wait()
+	}
+	t.started = true
+}
+
+// yield lets any task with an earlier event run first
+func (k *kernel) yield(time float64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	t := k.current
+	t.time = time
+	t.state = ready
+	k.switchTo(t)
+}
+
+// park blocks the current task until one of the channels changes
+func (k *kernel) park(channels ...watched) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	t := k.current
+	t.state = parked
+	t.channels = channels
+	k.switchTo(t)
+	t.channels = nil
+}
+
+// notify wakes the tasks parked on c
+func (k *kernel) notify(c watched) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, t := range k.tasks {
+		if t.state != parked {
+			continue
+		}
+		for _, d := range t.channels {
+			if d == c {
+				t.state = ready
+				break
+			}
+		}
+	}
+}
+
+// join blocks the current task until all of its children have exited
+func (k *kernel) join() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	t := k.current
+	if t.children > 0 {
+		t.state = joining
+		k.switchTo(t)
+	}
+}
+
+// drain blocks the current task until every other task has exited
+func (k *kernel) drain() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	t := k.current
+	if len(k.tasks) > 1 {
+		t.state = draining
+		k.switchTo(t)
+	}
+}
+
+// settle blocks the current task until every other task has started, by
+// then the processes created so far have called Init
+func (k *kernel) settle() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	t := k.current
+	t.state = settling
+	k.switchTo(t)
+}
+
+// await blocks the current task until p has exited
+func (k *kernel) await(p *task) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if p.state == exited {
+		return
+	}
+	t := k.current
+	t.state = awaiting
+	p.awaiter = t
+	k.switchTo(t)
+}
+
+// exit removes the current task and hands control to the next one
+func (k *kernel) exit() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	t := k.current
+	t.state = exited
+	for i, u := range k.tasks {
+		if u == t {
+			k.tasks = append(k.tasks[0:i], k.tasks[i+1:]...)
+			break
+		}
+	}
+	if t.promise != nil {
+		promises.Delete(t.promise)
+	}
+	if t.parent != nil {
+		t.parent.children--
+		if t.parent.state == joining && t.parent.children == 0 {
+			t.parent.state = ready
+		}
+	}
+	if t.awaiter != nil {
+		t.awaiter.state = ready
+	}
+	if len(k.tasks) == 1 && k.tasks[0].state == draining {
+		k.tasks[0].state = ready
+	}
+	if len(k.tasks) == 0 {
+		stopKernel(k)
+	}
+	k.schedule()
+}
+
+func awaitPromise(c interface{}) {
+	if v, ok := promises.Load(c); ok {
+		t := v.(*task)
+		t.k.await(t)
+	}
+}
+
+// async runs fn in a new goroutine. Under the deterministic kernel it is
+// scheduled like any other task and promise, the channel it fills, is
+// registered so that waiting on it hands control to fn.
+func async(g Globals, promise interface{}, fn func()) {
+	gl, ok := g.(*globals)
+	if !ok || gl.kernel == nil {
+		go fn()
+		return
+	}
+	spawn(gl.kernel, gl.curr, promise, fn)
+}
+
+// asyncAny is async for the helpers that aren't given a process, fn joins
+// the deterministic simulation in progress if there is one
+func asyncAny(promise interface{}, fn func()) {
+	k := runningKernel()
+	if k == nil {
+		go fn()
+		return
+	}
+	k.mu.Lock()
+	time := 0.0
+	if k.current != nil {
+		time = k.current.time
+	}
+	k.mu.Unlock()
+	spawn(k, time, promise, fn)
+}
+
+func spawn(k *kernel, time float64, promise interface{}, fn func()) {
+	t := k.add(nil, time)
+	t.promise = promise
+	promises.Store(promise, t)
+	go func() {
+		k.start(t)
+		defer k.exit()
+		fn()
+	}()
+}
+
+// orderEvent orders a channel event at time t relative to every other
+// process under the deterministic kernel
+func orderEvent(g Globals, t float64) {
+	if gl, ok := g.(*globals); ok && gl.kernel != nil {
+		gl.kernel.yield(t)
+	}
+}
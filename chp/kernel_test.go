@@ -0,0 +1,198 @@
+package chp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/param"
+	"git.broccolimicro.io/Broccoli/pr.git/chp/trace"
+)
+
+func splitDeterministic(t *testing.T, out string) []byte {
+	g, err := New(out, "example.prof")
+	assert.NoError(t, err)
+	g.SetTrace(trace.JSON)
+	g.SetDeterministic(true)
+
+	Cs, Cr := Chan[int]("C", 0)
+	Ls, Lr := Chan[int64]("L", 0)
+	Rs, Rr := ChanArr[int64]("R", 2, 1)
+
+	go SourceN[int](50, func(i int64) int { return int(i*i%3) % 2 }, g.Sub("src_C"), Cs)
+	go Source[int64](func(i int64) int64 { return i }, g.Sub("src"), Ls)
+	for i := 0; i < 2; i++ {
+		go Sink[int64](g.Sub("sink.%d", i), Rr[i])
+	}
+	go Split[int64](g.Sub("dut"), Cr, Lr, Rs)
+	g.Done()
+
+	result, err := os.ReadFile(filepath.Join(out, trace.JSONFile))
+	assert.NoError(t, err)
+	return result
+}
+
+func TestIntegrationDeterministic(t *testing.T) {
+	out := param.String(2, "test/chp/deterministic")
+
+	first := splitDeterministic(t, filepath.Join(out, "0"))
+	second := splitDeterministic(t, filepath.Join(out, "1"))
+	assert.NotEmpty(t, first)
+	assert.Equal(t, string(first), string(second))
+
+	events, err := trace.Load(filepath.Join(out, "0", trace.JSONFile))
+	assert.NoError(t, err)
+	count := 0
+	for _, e := range events {
+		if e.Dir == trace.Recv && e.Channel == "C" {
+			count++
+		}
+	}
+	assert.Equal(t, 50, count)
+}
+
+func TestIntegrationDeterministicDeadlock(t *testing.T) {
+	out := param.String(2, "test/chp/deterministic_deadlock")
+
+	g, err := New(out)
+	assert.NoError(t, err)
+	g.SetDeterministic(true)
+
+	As, Ar := Chan[int64]("A", 0)
+	Bs, Br := Chan[int64]("B", 0)
+
+	go func(g Globals) {
+		g.Init(Ar, Bs)
+		defer g.Done()
+		Ar.Recv()
+		Bs.Send(0)
+	}(g.Sub("left"))
+	go func(g Globals) {
+		g.Init(Br, As)
+		defer g.Done()
+		Br.Recv()
+		As.Send(0)
+	}(g.Sub("right"))
+	g.Done()
+
+	report, err := os.ReadFile(filepath.Join(out, "deadlock"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(report), "protocol deadlock, cycle between top.left, top.right")
+}
+
+func jitterEnergies(t *testing.T, out string) []float64 {
+	g, err := New(out, "example.prof")
+	assert.NoError(t, err)
+	g.SetSeed(1234)
+	g.SetTrace(trace.JSON)
+	g.SetDeterministic(true)
+
+	Ls, Lr := Chan[int64]("L", 0)
+	Rs, Rr := Chan[int64]("R", 0)
+
+	go SourceN[int64](100, func(i int64) int64 { return i }, g.Sub("src"), Ls)
+	go SinkN[int64](100, g.Sub("sink"), Rr)
+	go Buffer[int64](g.Sub("jitter"), Lr, Rs)
+	g.Done()
+
+	events, err := trace.Load(filepath.Join(out, trace.JSONFile))
+	assert.NoError(t, err)
+	var result []float64
+	for _, e := range events {
+		if e.Dir == trace.Cycle && e.Process == "top.jitter" {
+			result = append(result, e.Energy)
+		}
+	}
+	return result
+}
+
+func TestIntegrationDistribution(t *testing.T) {
+	out := param.String(2, "test/chp/distribution")
+
+	first := jitterEnergies(t, filepath.Join(out, "0"))
+	second := jitterEnergies(t, filepath.Join(out, "1"))
+	assert.Len(t, first, 100)
+	assert.Equal(t, first, second)
+
+	counts := map[float64]int{}
+	for _, e := range first {
+		counts[e]++
+	}
+	assert.Len(t, counts, 3)
+	assert.Greater(t, counts[10.0], counts[9.0])
+	assert.Greater(t, counts[10.0], counts[11.0])
+}
+
+func TestIntegrationProfileWarnings(t *testing.T) {
+	out := param.String(2, "test/chp/profile_warnings")
+
+	g, err := New(out, "example.prof")
+	assert.NoError(t, err)
+	early := g.Sub("early")
+	g.SetProfileWarnings(true)
+	assert.True(t, early.(*globals).profileWarnings)
+	early.Init()
+	early.Done()
+
+	sub := g.Sub("jitter")
+	p := sub.Init()
+	assert.Equal(t, 0.0, p.Find("d0L"))
+	assert.Equal(t, 0.0, p.Find("d0Q"))
+	assert.Equal(t, 0.0, p.Sample("d0Q"))
+	assert.Greater(t, p.Sample("e0"), 0.0)
+	sub.Done()
+	g.Done()
+
+	assert.Equal(t, map[string]bool{"d0L": true, "d0Q": false, "e0": true}, sub.(*globals).reads)
+}
+
+func joinDeterministic(t *testing.T, out string) []byte {
+	g, err := New(out, "example.prof")
+	assert.NoError(t, err)
+	g.SetTrace(trace.JSON)
+	g.SetDeterministic(true)
+
+	As, Ar := Chan[int64]("A", 0)
+	Bs, Br := Chan[int64]("B", 0)
+	Cs, Cr := Chan[int64]("C", 0)
+
+	go SourceN[int64](20, func(i int64) int64 { return i }, g.Sub("src_A"), As)
+	go SourceN[int64](20, func(i int64) int64 { return 2 * i }, g.Sub("src_B"), Bs)
+	go Sink[int64](g.Sub("sink"), Cr)
+	go func(g Globals) {
+		g.Init(Ar, Br, Cs)
+		defer g.Done()
+		for {
+			On(Ar, Br).Send()
+			a, _ := OnAction(Ar.Recv).Recv()
+			b, _ := Br.Recv()
+			Cs.Send(a + b)
+		}
+	}(g.Sub("join"))
+	g.Done()
+
+	result, err := os.ReadFile(filepath.Join(out, trace.JSONFile))
+	assert.NoError(t, err)
+	return result
+}
+
+func TestIntegrationDeterministicOn(t *testing.T) {
+	out := param.String(2, "test/chp/deterministic_on")
+
+	first := joinDeterministic(t, filepath.Join(out, "0"))
+	second := joinDeterministic(t, filepath.Join(out, "1"))
+	assert.NotEmpty(t, first)
+	assert.Equal(t, string(first), string(second))
+
+	events, err := trace.Load(filepath.Join(out, "0", trace.JSONFile))
+	assert.NoError(t, err)
+	var values []string
+	for _, e := range events {
+		if e.Dir == trace.Recv && e.Channel == "C" {
+			values = append(values, e.Value.Text)
+		}
+	}
+	assert.Len(t, values, 20)
+	assert.Equal(t, "57", values[len(values)-1])
+}
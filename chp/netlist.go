@@ -0,0 +1,277 @@
+package chp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// NetPort is a declared port of a block instance and the channel bound to it
+type NetPort struct {
+	Name string    `json:"name"`
+	Dir  Direction `json:"dir"`
+	Type string    `json:"type"`
+	// index into Netlist.Channels, -1 if the channel was never initialized
+	Channel int `json:"channel"`
+}
+
+// NetProcess is a process created with Sub
+type NetProcess struct {
+	Name   string `json:"name"`
+	Parent string `json:"parent,omitempty"`
+	// the block this process instantiates, empty if it isn't one
+	Block string    `json:"block,omitempty"`
+	Ports []NetPort `json:"ports,omitempty"`
+	// indices into Netlist.Channels of the channels passed to Init
+	Channels []int `json:"channels,omitempty"`
+}
+
+// NetChannel is a channel with the processes on either side, which are
+// empty if that side was never initialized
+type NetChannel struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Sender   string `json:"sender"`
+	Receiver string `json:"receiver"`
+}
+
+// Netlist is the network of processes and channels that was actually built
+type Netlist struct {
+	Processes []NetProcess `json:"processes"`
+	Channels  []NetChannel `json:"channels"`
+}
+
+func (d Direction) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Direction) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "input":
+		*d = Input
+	case "output":
+		*d = Output
+	default:
+		return fmt.Errorf("unknown direction '%s'", text)
+	}
+	return nil
+}
+
+// flatten lists the channels behind the ports passed to Init
+func flatten(ports []interface{}) []watched {
+	var result []watched
+	for _, port := range ports {
+		if port == nil {
+			continue
+		} else if gd, ok := guardOf(port); ok {
+			c, _ := gd.endpoint()
+			result = append(result, c)
+		} else if reflect.TypeOf(port).Kind() == reflect.Slice || reflect.TypeOf(port).Kind() == reflect.Array {
+			items := reflect.ValueOf(port)
+			for i := 0; i < items.Len(); i++ {
+				result = append(result, flatten([]interface{}{items.Index(i).Interface()})...)
+			}
+		}
+	}
+	return result
+}
+
+// netlist collects the processes and the channels they initialized so far
+func (m *monitor) netlist() *Netlist {
+	type entry struct {
+		c watched
+		NetChannel
+	}
+
+	m.mu.Lock()
+	processes := append([]*globals{}, m.processes...)
+	channels := append([]watched{}, m.channels...)
+	m.mu.Unlock()
+
+	entries := make([]entry, len(channels))
+	for i, c := range channels {
+		entries[i].c = c
+		entries[i].Name = c.chanName()
+		entries[i].Type = c.typeName()
+		s, r := c.endpoints()
+		if s != nil {
+			entries[i].Sender = s.name
+		}
+		if r != nil {
+			entries[i].Receiver = r.name
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		} else if a.Sender != b.Sender {
+			return a.Sender < b.Sender
+		}
+		return a.Receiver < b.Receiver
+	})
+
+	n := &Netlist{}
+	index := map[watched]int{}
+	for i, e := range entries {
+		index[e.c] = i
+		n.Channels = append(n.Channels, e.NetChannel)
+	}
+
+	sort.SliceStable(processes, func(i, j int) bool {
+		return processes[i].name < processes[j].name
+	})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, g := range processes {
+		p := NetProcess{Name: g.name}
+		if g.parent != nil {
+			p.Parent = g.parent.name
+		}
+		for _, c := range flatten(g.ports) {
+			if j, ok := index[c]; ok {
+				p.Channels = append(p.Channels, j)
+			}
+		}
+		if g.block != nil {
+			p.Block = g.block.Name
+			for i, port := range g.block.Ports {
+				np := NetPort{
+					Name:    port.Name,
+					Dir:     port.Dir,
+					Type:    port.Type.String(),
+					Channel: -1,
+				}
+				if gd, ok := guardOf(g.bindings[i]); ok {
+					c, _ := gd.endpoint()
+					if j, ok := index[c]; ok {
+						np.Channel = j
+					}
+				}
+				p.Ports = append(p.Ports, np)
+			}
+		}
+		n.Processes = append(n.Processes, p)
+	}
+	return n
+}
+
+func (n *Netlist) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "processes\n")
+	for _, p := range n.Processes {
+		fmt.Fprintf(&buf, "\t%s", p.Name)
+		if p.Block != "" {
+			fmt.Fprintf(&buf, "\t%s", p.Block)
+		}
+		fmt.Fprintf(&buf, "\n")
+		for _, port := range p.Ports {
+			name := "<unbound>"
+			if port.Channel >= 0 {
+				name = n.Channels[port.Channel].Name
+			}
+			fmt.Fprintf(&buf, "\t\t%s %s %s\t%s\n", port.Dir, port.Name, port.Type, name)
+		}
+	}
+
+	fmt.Fprintf(&buf, "\nchannels\n")
+	for _, c := range n.Channels {
+		s, r := c.Sender, c.Receiver
+		if s == "" {
+			s = "nobody"
+		}
+		if r == "" {
+			r = "nobody"
+		}
+		fmt.Fprintf(&buf, "\t%s %s\t%s -> %s\n", c.Name, c.Type, s, r)
+	}
+	return buf.String()
+}
+
+// Dangling lists the channels with a side that no process initialized
+func (n *Netlist) Dangling() []NetChannel {
+	var result []NetChannel
+	for _, c := range n.Channels {
+		if c.Sender == "" || c.Receiver == "" {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func (n *Netlist) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(n)
+}
+
+// WriteDOT draws the network for Graphviz. Every process with children,
+// like a block instance, is a cluster around them and every channel is an
+// edge from its sender to its receiver. A missing side is drawn as a red
+// point.
+func (n *Netlist) WriteDOT(w io.Writer) error {
+	children := map[string][]string{}
+	var roots []string
+	for _, p := range n.Processes {
+		if p.Parent == "" {
+			roots = append(roots, p.Name)
+		} else {
+			children[p.Parent] = append(children[p.Parent], p.Name)
+		}
+	}
+	blocks := map[string]string{}
+	ports := map[string]bool{}
+	for _, p := range n.Processes {
+		blocks[p.Name] = p.Block
+		ports[p.Name] = len(p.Channels) > 0
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "digraph netlist {\n")
+	fmt.Fprintf(&buf, "\tnode [shape=box];\n")
+
+	var visit func(name, indent string)
+	visit = func(name, indent string) {
+		if len(children[name]) == 0 {
+			fmt.Fprintf(&buf, "%s%q;\n", indent, name)
+			return
+		}
+		label := name
+		if blocks[name] != "" {
+			label += " (" + blocks[name] + ")"
+		}
+		fmt.Fprintf(&buf, "%ssubgraph %q {\n", indent, "cluster_"+name)
+		fmt.Fprintf(&buf, "%s\tlabel=%q;\n", indent, label)
+		if ports[name] {
+			fmt.Fprintf(&buf, "%s\t%q;\n", indent, name)
+		}
+		for _, child := range children[name] {
+			visit(child, indent+"\t")
+		}
+		fmt.Fprintf(&buf, "%s}\n", indent)
+	}
+	for _, name := range roots {
+		visit(name, "\t")
+	}
+
+	for i, c := range n.Channels {
+		s, r := c.Sender, c.Receiver
+		if s == "" {
+			s = fmt.Sprintf("dangling.%d", i)
+			fmt.Fprintf(&buf, "\t%q [shape=point, color=red];\n", s)
+		}
+		if r == "" {
+			r = fmt.Sprintf("dangling.%d", i)
+			fmt.Fprintf(&buf, "\t%q [shape=point, color=red];\n", r)
+		}
+		fmt.Fprintf(&buf, "\t%q -> %q [label=%q];\n", s, r, c.Name+" "+c.Type)
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
@@ -0,0 +1,105 @@
+package chp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/param"
+)
+
+func TestIntegrationNetlistDangling(t *testing.T) {
+	profile := param.String(1, "example.prof")
+	out := param.String(2, "test/chp/netlist_dangling")
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	g.SetDeterministic(true)
+
+	Ls, Lr := Chan[int]("L", 0)
+	// nobody receives from R
+	Rs, _ := Chan[int]("R", 0)
+
+	go SourceN(10, Values(1, 2, 3), g.Sub("src"), Ls)
+	go Buffer(g.Sub("buf"), Lr, Rs)
+	g.Done()
+
+	n := g.Netlist()
+	assert.Equal(t, []NetChannel{{"R", "int", "top.buf", ""}}, n.Dangling())
+	assert.Equal(t, []int{0, 1}, n.Processes[1].Channels)
+
+	dot, err := os.ReadFile(filepath.Join(out, "netlist.dot"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(dot), "\t\"top.src\" -> \"top.buf\" [label=\"L int\"];\n")
+	assert.Contains(t, string(dot), "\t\"dangling.1\" [shape=point, color=red];\n")
+	assert.Contains(t, string(dot), "\t\"top.buf\" -> \"dangling.1\" [label=\"R int\"];\n")
+
+	data, err := os.ReadFile(filepath.Join(out, "netlist.json"))
+	assert.NoError(t, err)
+	var loaded Netlist
+	assert.NoError(t, json.Unmarshal(data, &loaded))
+	assert.Equal(t, n, &loaded)
+
+	report, err := os.ReadFile(filepath.Join(out, "deadlock"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(report), "dangling channel, R has no process on the other side")
+}
+
+func TestIntegrationCheck(t *testing.T) {
+	out := param.String(2, "test/chp/check")
+
+	for _, deterministic := range []bool{false, true} {
+		g, err := New(filepath.Join(out, fmt.Sprint(deterministic)))
+		assert.NoError(t, err)
+		g.SetDeterministic(deterministic)
+
+		Ls, Lr := Chan[int]("L", 0)
+		Rs, _ := Chan[int]("R", 0)
+		_, Xr := Chan[int]("X", 0)
+
+		go SourceN(10, Values(1, 2, 3), g.Sub("src"), Ls)
+		go Buffer(g.Sub("buf"), Lr, Rs)
+		go Sink(g.Sub("sink"), Xr)
+
+		// found before the run deadlocks on them
+		err = g.Check()
+		assert.EqualError(t, err, "dangling channel R, nobody receives from top.buf\n"+
+			"dangling channel X, nobody sends to top.sink", "deterministic %v", deterministic)
+		g.Done()
+	}
+}
+
+func TestUnitNetlistDOT(t *testing.T) {
+	n := &Netlist{
+		Processes: []NetProcess{
+			{Name: "top", Channels: []int{0}},
+			{Name: "top.a", Parent: "top", Block: "Pipe"},
+			{Name: "top.a.b", Parent: "top.a", Channels: []int{0}},
+		},
+		Channels: []NetChannel{
+			{"L", "bool", "top", "top.a.b"},
+		},
+	}
+
+	var buf strings.Builder
+	assert.NoError(t, n.WriteDOT(&buf))
+	assert.Equal(t, `digraph netlist {
+	node [shape=box];
+	subgraph "cluster_top" {
+		label="top";
+		"top";
+		subgraph "cluster_top.a" {
+			label="top.a (Pipe)";
+			"top.a.b";
+		}
+	}
+	"top" -> "top.a.b" [label="L bool"];
+}
+`, buf.String())
+	assert.Empty(t, n.Dangling())
+}
@@ -0,0 +1,57 @@
+package chp
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// lockedSource lets a process share its random stream with the goroutines
+// behind Offer, Expect, Watch and Read. It sets drawn, if not nil, once a
+// number is drawn.
+type lockedSource struct {
+	mu    sync.Mutex
+	src   rand.Source64
+	drawn *atomic.Bool
+}
+
+func (s *lockedSource) draw() {
+	if s.drawn != nil && !s.drawn.Load() {
+		s.drawn.Store(true)
+	}
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.draw()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.draw()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// newRand derives the random stream of a process from the seed of the run
+// and the name of the process. Adding or reordering processes doesn't
+// change the values drawn by the others.
+func newRand(seed int64, name string, drawn *atomic.Bool) *rand.Rand {
+	h := fnv.New64a()
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(seed))
+	h.Write(b[:])
+	h.Write([]byte(name))
+	src := rand.NewSource(int64(h.Sum64())).(rand.Source64)
+	return rand.New(&lockedSource{src: src, drawn: drawn})
+}
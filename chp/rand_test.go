@@ -0,0 +1,71 @@
+package chp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/param"
+	"git.broccolimicro.io/Broccoli/pr.git/chp/trace"
+)
+
+func TestUnitRand(t *testing.T) {
+	a := newRand(42, "top.src", nil)
+	b := newRand(42, "top.src", nil)
+	c := newRand(42, "top.sink", nil)
+	d := newRand(43, "top.src", nil)
+
+	x := a.Int63()
+	assert.Equal(t, x, b.Int63())
+	assert.NotEqual(t, x, c.Int63())
+	assert.NotEqual(t, x, d.Int63())
+}
+
+func seededRun(t *testing.T, out string, seed int64) []byte {
+	g, err := New(out)
+	assert.NoError(t, err)
+	g.SetSeed(seed)
+	g.SetTrace(trace.JSON)
+	g.SetDeterministic(true)
+	g.RandomTiming(1)
+
+	Ls, Lr := Chan[int64]("L", 0)
+
+	src := g.Sub("src")
+	go SourceN(20, RandomInt64(src.Rand(), -100, 100), src, Ls)
+	go Sink(g.Sub("sink"), Lr)
+	g.Done()
+
+	result, err := os.ReadFile(filepath.Join(out, trace.JSONFile))
+	assert.NoError(t, err)
+	return result
+}
+
+func TestIntegrationSeed(t *testing.T) {
+	out := param.String(2, "test/chp/seed")
+
+	first := seededRun(t, filepath.Join(out, "0"), 1234)
+	second := seededRun(t, filepath.Join(out, "1"), 1234)
+	assert.Equal(t, string(first), string(second))
+
+	seed, err := os.ReadFile(filepath.Join(out, "0", "seed"))
+	assert.NoError(t, err)
+	assert.Equal(t, "1234\n", string(seed))
+
+	// a run without randomness doesn't depend on its seed
+	g, err := New(filepath.Join(out, "2"))
+	assert.NoError(t, err)
+	Ls, Lr := Chan[int64]("L", 0)
+	go SourceN[int64](20, func(i int64) int64 { return i }, g.Sub("src"), Ls)
+	go Sink(g.Sub("sink"), Lr)
+	g.Done()
+	_, err = os.Stat(filepath.Join(out, "2", "seed"))
+	assert.True(t, os.IsNotExist(err))
+
+	g, err = New(filepath.Join(out, "3"))
+	assert.NoError(t, err)
+	g.Sub("src")
+	assert.Panics(t, func() { g.SetSeed(1) })
+}
@@ -0,0 +1,276 @@
+package chp
+
+import (
+	"fmt"
+	"reflect"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/timing"
+)
+
+// guard is a probe that Select can wait on, implemented by every Sender and
+// Receiver
+type guard interface {
+	endpoint() (watched, bool)
+	// enabled reports whether the probe is true and the simulated time at
+	// which it became true, dead is set if the other side has closed
+	enabled() (ok bool, dead bool, t float64)
+	watch(w chan struct{})
+	unwatch(w chan struct{})
+}
+
+// Policy chooses the winner among guards that were enabled by the time the
+// process started waiting, or at the same time after that. ties holds their
+// indices in increasing order and arrival the times they became enabled.
+type Policy func(g Globals, ties []int, arrival []float64) int
+
+// Priority always chooses the guard listed first
+func Priority(g Globals, ties []int, arrival []float64) int {
+	return ties[0]
+}
+
+// FirstCome chooses the guard that was enabled earliest, then the one
+// listed first
+func FirstCome(g Globals, ties []int, arrival []float64) int {
+	winner := 0
+	for i := range ties {
+		if arrival[i] < arrival[winner] {
+			winner = i
+		}
+	}
+	return ties[winner]
+}
+
+// Random chooses uniformly using the random stream of the process
+func Random(g Globals, ties []int, arrival []float64) int {
+	return ties[g.Rand().Intn(len(ties))]
+}
+
+// RoundRobin returns a policy that prefers the guard after the previous
+// winner
+func RoundRobin() Policy {
+	last := -1
+	return func(g Globals, ties []int, arrival []float64) int {
+		winner := ties[0]
+		for _, i := range ties {
+			if i > last {
+				winner = i
+				break
+			}
+		}
+		last = winner
+		return winner
+	}
+}
+
+// guardOf finds the channel behind a probe, looking through wrappers like
+// those in chp/bd that embed a Sender or Receiver
+func guardOf(b interface{}) (guard, bool) {
+	if bi, ok := b.(guard); ok {
+		return bi, true
+	}
+	v := reflect.ValueOf(b)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct && v.NumField() == 1 && v.Type().Field(0).Anonymous && v.Field(0).CanInterface() {
+		return guardOf(v.Field(0).Interface())
+	}
+	return nil, false
+}
+
+func guards(B ...interface{}) (result []guard) {
+	for _, b := range B {
+		if bi, ok := guardOf(b); ok {
+			result = append(result, bi)
+		} else if reflect.TypeOf(b).Kind() == reflect.Slice || reflect.TypeOf(b).Kind() == reflect.Array {
+			items := reflect.ValueOf(b)
+			for i := 0; i < items.Len(); i++ {
+				if bi, ok := guardOf(items.Index(i).Interface()); ok {
+					result = append(result, bi)
+				} else {
+					panic(Misconfigured)
+				}
+			}
+		} else {
+			panic(Misconfigured)
+		}
+	}
+	return
+}
+
+// Select blocks until at least one of the probes is true, like CHP's
+// [#L -> ... [] #R -> ...], and returns the index of the one that was
+// enabled earliest in simulated time along with that time. Probes may be
+// Senders, Receivers, the channels in chp/bd that wrap them or slices of
+// them, which are flattened in order. Ties go to the probe that was enabled
+// first. Select doesn't complete the winning action, follow it with the
+// matching Send or Recv.
+//
+// Simulated time only orders the probes that are enabled when Select looks
+// at them. Without SetDeterministic(true), which probes those are depends
+// on which goroutines the Go scheduler happened to run first, so the
+// winner and the cycle it is chosen in can vary between runs even with the
+// same seed. Use the deterministic kernel for reproducible arbitration.
+func Select(g Globals, probes ...interface{}) (int, float64) {
+	return SelectWith(g, FirstCome, probes...)
+}
+
+// SelectWith is Select with a policy to break ties. Like Select, the
+// policy only sees a reproducible set of candidates under
+// SetDeterministic(true).
+func SelectWith(g Globals, policy Policy, probes ...interface{}) (int, float64) {
+	G := guards(probes...)
+	if len(G) == 0 {
+		panic(Misconfigured)
+	}
+	winner, t, _ := arbitrate(g, policy, G)
+	return winner, t
+}
+
+// arbitrate waits for at least one guard and chooses between those enabled
+// at the earliest time. Guards enabled before the process started waiting
+// all tie. contested reports how many other guards were enabled at exactly
+// the same time as the winner after the process started waiting, racing
+// each other in an arbiter.
+func arbitrate(g Globals, policy Policy, G []guard) (winner int, t float64, contested int) {
+	start := g.Curr()
+	orderEvent(g, start)
+	g.Timing()
+
+	w := make(chan struct{}, 1)
+	channels := make([]watched, len(G))
+	ops := make([]*pending, len(G))
+	for i, gd := range G {
+		gd.watch(w)
+		c, send := gd.endpoint()
+		channels[i] = c
+		ops[i] = beginOp(g, c, send, "select")
+	}
+	defer func() {
+		for _, gd := range G {
+			gd.unwatch(w)
+		}
+	}()
+
+	for {
+		var ties []int
+		var arrival []float64
+		alive := 0
+		for i, gd := range G {
+			ok, dead, at := gd.enabled()
+			if !dead {
+				alive++
+			}
+			if !ok {
+				continue
+			}
+			clamped := at
+			if start > clamped {
+				clamped = start
+			}
+			if len(ties) == 0 || clamped < t {
+				ties = []int{i}
+				arrival = []float64{at}
+				t = clamped
+			} else if clamped == t {
+				ties = append(ties, i)
+				arrival = append(arrival, at)
+			}
+		}
+
+		if len(ties) > 0 {
+			winner = ties[0]
+			if len(ties) > 1 {
+				winner = policy(g, ties, arrival)
+			}
+			for i, j := range ties {
+				if j != winner && arrival[i] >= start && arrival[i] == t {
+					contested++
+				}
+			}
+			for _, op := range ops {
+				endOp(g, op)
+			}
+			if g.Debug() {
+				fmt.Printf("%f ns\t\t[%d]\t\t%s\n", t, winner, g.Name())
+			}
+			return winner, t - g.Curr(), contested
+		} else if alive == 0 {
+			for _, op := range ops {
+				failOp(g, op)
+			}
+			panic(timing.Deadlock)
+		}
+
+		if gl, ok := g.(*globals); ok && gl.kernel != nil {
+			gl.kernel.park(channels...)
+		} else {
+			<-w
+		}
+	}
+}
+
+func (c *channel[T]) watch(w chan struct{}) {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	c.watchers = append(c.watchers, w)
+}
+
+func (c *channel[T]) unwatch(w chan struct{}) {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	for i, v := range c.watchers {
+		if v == w {
+			c.watchers = append(c.watchers[0:i], c.watchers[i+1:]...)
+			break
+		}
+	}
+}
+
+func (s *sender[T]) endpoint() (watched, bool) {
+	return s.c, true
+}
+
+// a send is enabled once there is room in the channel
+func (s *sender[T]) enabled() (bool, bool, float64) {
+	s.c.cond.L.Lock()
+	defer s.c.cond.L.Unlock()
+	if s.c.sendDead() {
+		return false, true, 0
+	} else if s.c.full() {
+		return false, false, 0
+	}
+	return true, false, s.c.readyTime
+}
+
+func (s *sender[T]) watch(w chan struct{}) {
+	s.c.watch(w)
+}
+
+func (s *sender[T]) unwatch(w chan struct{}) {
+	s.c.unwatch(w)
+}
+
+func (r *receiver[T]) endpoint() (watched, bool) {
+	return r.c, false
+}
+
+// a receive is enabled once a token has arrived
+func (r *receiver[T]) enabled() (bool, bool, float64) {
+	r.c.cond.L.Lock()
+	defer r.c.cond.L.Unlock()
+	if r.c.empty() {
+		return false, r.c.recvDead(), 0
+	} else if r.c.recvBlocked {
+		return false, true, 0
+	}
+	return true, false, r.c.buffer[r.c.read].T
+}
+
+func (r *receiver[T]) watch(w chan struct{}) {
+	r.c.watch(w)
+}
+
+func (r *receiver[T]) unwatch(w chan struct{}) {
+	r.c.unwatch(w)
+}
@@ -0,0 +1,97 @@
+package chp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/param"
+)
+
+func TestIntegrationSelect(t *testing.T) {
+	out := param.String(2, "test/chp/select")
+
+	g, err := New(out)
+	assert.NoError(t, err)
+	g.SetDeterministic(true)
+
+	Ls, Lr := ChanArr[int64]("L", 2, 0)
+
+	go func(g Globals) {
+		g.Init(Ls[0])
+		defer g.Done()
+		Ls[0].Send(0, 5)
+	}(g.Sub("late"))
+	go func(g Globals) {
+		g.Init(Ls[1])
+		defer g.Done()
+		Ls[1].Send(1, 2)
+	}(g.Sub("early"))
+
+	var winners []int
+	var times []float64
+	go func(g Globals) {
+		g.Init(Lr)
+		defer g.Done()
+		for {
+			i, t := Select(g, Lr)
+			winners = append(winners, i)
+			times = append(times, t)
+			Lr[i].Recv()
+		}
+	}(g.Sub("select"))
+	g.Done()
+
+	assert.Equal(t, []int{1, 0}, winners)
+	assert.Equal(t, []float64{2, 5}, times)
+}
+
+func TestIntegrationSelectTie(t *testing.T) {
+	out := param.String(2, "test/chp/select_tie")
+
+	g, err := New(out)
+	assert.NoError(t, err)
+	g.SetDeterministic(true)
+
+	Ls, Lr := ChanArr[int64]("L", 3, 0)
+	for i := range Ls {
+		go func(g Globals, L Sender[int64]) {
+			g.Init(L)
+			defer g.Done()
+			for j := 0; j < 2; j++ {
+				L.Send(0)
+			}
+		}(g.Sub("src.%d", i), Ls[i])
+	}
+
+	var winners []int
+	go func(g Globals) {
+		g.Init(Lr)
+		defer g.Done()
+		policy := RoundRobin()
+		for {
+			i, _ := SelectWith(g, policy, Lr)
+			winners = append(winners, i)
+			Lr[i].Recv()
+		}
+	}(g.Sub("select"))
+	g.Done()
+
+	assert.Equal(t, []int{0, 1, 2, 0, 1, 2}, winners)
+}
+
+func TestUnitPolicy(t *testing.T) {
+	g, err := New("test/chp/policy", "", "top", "", "1")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, Priority(g, []int{2, 3}, []float64{1, 0}))
+	assert.Equal(t, 3, FirstCome(g, []int{2, 3}, []float64{1, 0}))
+	assert.Equal(t, 2, FirstCome(g, []int{2, 3}, []float64{0, 0}))
+	assert.Contains(t, []int{2, 3}, Random(g, []int{2, 3}, []float64{0, 0}))
+
+	rr := RoundRobin()
+	assert.Equal(t, 0, rr(g, []int{0, 1, 2}, nil))
+	assert.Equal(t, 2, rr(g, []int{0, 2}, nil))
+	assert.Equal(t, 0, rr(g, []int{0, 1}, nil))
+	assert.Equal(t, 1, rr(g, []int{0, 1}, nil))
+}
@@ -6,19 +6,22 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func RandomBool() func(i int64) bool {
+// The random generators draw from r, usually the Rand of the process that
+// uses them, so that a run can be replayed from its seed.
+
+func RandomBool(r *rand.Rand) func(i int64) bool {
 	return func(i int64) bool {
-		return rand.Intn(2) == 1
+		return r.Intn(2) == 1
 	}
 }
 
-func RandomInt(lower, upper int) func(i int64) int {
+func RandomInt(r *rand.Rand, lower, upper int) func(i int64) int {
 	return func(i int64) int {
 		low := lower
 		high := upper
 
 		// ensure variety in digit-stream length as well
-		length := (1<<rand.Intn(32))-1
+		length := (1<<r.Intn(32))-1
 
 		if length < high {
 			high = length
@@ -29,20 +32,20 @@ func RandomInt(lower, upper int) func(i int64) int {
 		}
 
 		if high-low > 0 {		
-			low += rand.Intn(high-low)
+			low += r.Intn(high-low)
 		}
 	
 		return low
 	}
 }
 
-func RandomInt64(lower, upper int64) func(i int64) int64 {
+func RandomInt64(r *rand.Rand, lower, upper int64) func(i int64) int64 {
 	return func(i int64) int64 {
 		low := lower
 		high := upper
 
 		// ensure variety in digit-stream length as well
-		length := (int64(1)<<rand.Int63n(64))-1
+		length := (int64(1)<<r.Int63n(64))-1
 
 		if length < high {
 			high = length
@@ -53,7 +56,7 @@ func RandomInt64(lower, upper int64) func(i int64) int64 {
 		}
 
 		if high-low > 0 {		
-			low += rand.Int63n(high-low)
+			low += r.Int63n(high-low)
 		}
 
 		return low
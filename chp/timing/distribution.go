@@ -0,0 +1,146 @@
+package timing
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// Distribution is the value of a profile entry. Components draw a new
+// sample for every cycle to model variation. String returns the
+// distribution as it is written in a profile.
+type Distribution interface {
+	Mean() float64
+	Sample(r *rand.Rand) float64
+	String() string
+}
+
+// Constant always has the same value and never consumes random numbers
+type Constant float64
+
+func (d Constant) Mean() float64 {
+	return float64(d)
+}
+
+func (d Constant) Sample(r *rand.Rand) float64 {
+	return float64(d)
+}
+
+func (d Constant) String() string {
+	return fmt.Sprintf("%g", float64(d))
+}
+
+// Normal is written normal(mean, stddev) in a profile. Samples are clamped
+// at zero since delays and energies can't be negative.
+type Normal struct {
+	Mu    float64
+	Sigma float64
+}
+
+// Mean is the mean of the clamped samples, which is larger than Mu when
+// the distribution has a meaningful mass below zero
+func (d Normal) Mean() float64 {
+	if d.Sigma <= 0 {
+		return math.Max(0, d.Mu)
+	}
+	z := d.Mu / d.Sigma
+	cdf := math.Erfc(-z/math.Sqrt2) / 2
+	pdf := math.Exp(-z*z/2) / math.Sqrt(2*math.Pi)
+	return d.Mu*cdf + d.Sigma*pdf
+}
+
+func (d Normal) Sample(r *rand.Rand) float64 {
+	return math.Max(0, d.Mu+d.Sigma*r.NormFloat64())
+}
+
+func (d Normal) String() string {
+	return fmt.Sprintf("normal(%g, %g)", d.Mu, d.Sigma)
+}
+
+// Uniform is written uniform(min, max) in a profile
+type Uniform struct {
+	Min float64
+	Max float64
+}
+
+func (d Uniform) Mean() float64 {
+	return (d.Min + d.Max) / 2
+}
+
+func (d Uniform) Sample(r *rand.Rand) float64 {
+	return d.Min + (d.Max-d.Min)*r.Float64()
+}
+
+func (d Uniform) String() string {
+	return fmt.Sprintf("uniform(%g, %g)", d.Min, d.Max)
+}
+
+// LogNormal is written lognormal(mu, sigma) in a profile, the parameters of
+// the normal distribution of the logarithm of the value
+type LogNormal struct {
+	Mu    float64
+	Sigma float64
+}
+
+func (d LogNormal) Mean() float64 {
+	return math.Exp(d.Mu + d.Sigma*d.Sigma/2)
+}
+
+func (d LogNormal) Sample(r *rand.Rand) float64 {
+	return math.Exp(d.Mu + d.Sigma*r.NormFloat64())
+}
+
+func (d LogNormal) String() string {
+	return fmt.Sprintf("lognormal(%g, %g)", d.Mu, d.Sigma)
+}
+
+// Histogram is an empirical distribution written
+// histogram(value: weight, ...) in a profile. Each value is drawn with
+// probability proportional to its weight.
+type Histogram struct {
+	Values  []float64
+	Weights []float64
+}
+
+func (d Histogram) total() float64 {
+	total := 0.0
+	for _, w := range d.Weights {
+		total += w
+	}
+	return total
+}
+
+func (d Histogram) Mean() float64 {
+	total := d.total()
+	if total == 0 {
+		return 0
+	}
+	mean := 0.0
+	for i, v := range d.Values {
+		mean += v * d.Weights[i]
+	}
+	return mean / total
+}
+
+func (d Histogram) Sample(r *rand.Rand) float64 {
+	if len(d.Values) == 0 {
+		return 0
+	}
+	x := r.Float64() * d.total()
+	for i, w := range d.Weights {
+		x -= w
+		if x < 0 {
+			return d.Values[i]
+		}
+	}
+	return d.Values[len(d.Values)-1]
+}
+
+func (d Histogram) String() string {
+	bins := make([]string, len(d.Values))
+	for i, v := range d.Values {
+		bins[i] = fmt.Sprintf("%g: %g", v, d.Weights[i])
+	}
+	return "histogram(" + strings.Join(bins, ", ") + ")"
+}
@@ -0,0 +1,112 @@
+package timing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Helpers for the actions in parser.peg. The parser keeps the constants and
+// the stack of included files in its global store so that they are shared
+// with the files it includes.
+
+// units are normalized to ns and fJ
+var units = map[string]float64{
+	"ps": 1e-3,
+	"ns": 1,
+	"fJ": 1,
+	"pJ": 1e3,
+}
+
+// parseInclude is Parse, assigned in init since the grammar refers to it
+// through include
+var parseInclude func(filename string, b []byte, opts ...Option) (interface{}, error)
+
+func init() {
+	parseInclude = Parse
+}
+
+func (c *current) constants() map[string]float64 {
+	m, ok := c.globalStore["constants"].(map[string]float64)
+	if !ok {
+		m = make(map[string]float64)
+		c.globalStore["constants"] = m
+	}
+	return m
+}
+
+// included is the set of files already included anywhere in the profile set
+func (c *current) included() map[string]bool {
+	m, ok := c.globalStore["seen"].(map[string]bool)
+	if !ok {
+		m = make(map[string]bool)
+		c.globalStore["seen"] = m
+	}
+	return m
+}
+
+func (c *current) define(name string, value float64) error {
+	m := c.constants()
+	if _, ok := m[name]; ok {
+		return fmt.Errorf("constant '%s' is defined twice", name)
+	}
+	m[name] = value
+	return nil
+}
+
+func (c *current) lookup(name string) (float64, error) {
+	value, ok := c.constants()[name]
+	if !ok {
+		return 0, fmt.Errorf("undefined constant '%s'", name)
+	}
+	return value, nil
+}
+
+// include parses another file relative to the one being parsed and returns
+// its profiles, which may extend profiles from any of the files. A file
+// included more than once, like a characterization shared by several
+// components, is only read the first time.
+func (c *current) include(path string) ([]entry, error) {
+	files, _ := c.globalStore["files"].([]string)
+	dir := "."
+	if len(files) > 0 {
+		dir = filepath.Dir(files[len(files)-1])
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	for _, f := range files {
+		if f == path {
+			return nil, fmt.Errorf("'%s' includes itself", path)
+		}
+	}
+	seen := c.included()
+	if seen[path] {
+		return nil, nil
+	}
+	seen[path] = true
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result, err := parseInclude(path, b,
+		GlobalStore("included", true),
+		GlobalStore("constants", c.constants()),
+		GlobalStore("seen", seen),
+		GlobalStore("files", append(files[0:len(files):len(files)], path)))
+	if err != nil {
+		return nil, err
+	}
+	return result.([]entry), nil
+}
+
+// ParseProfileSet parses a whole profile set from b, files it includes are
+// found relative to filename
+func ParseProfileSet(filename string, b []byte) (ProfileSet, error) {
+	p, err := Parse(filename, b, GlobalStore("files", []string{filepath.Clean(filename)}))
+	if err != nil {
+		return nil, err
+	}
+	return p.(ProfileSet), nil
+}
@@ -40,18 +40,18 @@ var g = &grammar{
 						},
 						&labeledExpr{
 							pos:   position{line: 12, col: 9, offset: 114},
-							label: "pset",
+							label: "entries",
 							expr: &ruleRefExpr{
-								pos:  position{line: 12, col: 14, offset: 119},
+								pos:  position{line: 12, col: 17, offset: 122},
 								name: "ProfileSet",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 12, col: 25, offset: 130},
+							pos:  position{line: 12, col: 28, offset: 133},
 							name: "_",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 12, col: 27, offset: 132},
+							pos:  position{line: 12, col: 30, offset: 135},
 							name: "EOF",
 						},
 					},
@@ -60,201 +60,1142 @@ var g = &grammar{
 		},
 		{
 			name: "ProfileSet",
-			pos:  position{line: 16, col: 1, offset: 172},
+			pos:  position{line: 20, col: 1, offset: 321},
 			expr: &actionExpr{
-				pos: position{line: 16, col: 14, offset: 185},
+				pos: position{line: 20, col: 14, offset: 334},
 				run: (*parser).callonProfileSet1,
 				expr: &seqExpr{
-					pos: position{line: 16, col: 14, offset: 185},
+					pos: position{line: 20, col: 14, offset: 334},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 16, col: 14, offset: 185},
+							pos:        position{line: 20, col: 14, offset: 334},
 							val:        "{",
 							ignoreCase: false,
 							want:       "\"{\"",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 16, col: 18, offset: 189},
+							pos:  position{line: 20, col: 18, offset: 338},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 16, col: 20, offset: 191},
+							pos:   position{line: 20, col: 20, offset: 340},
 							label: "items",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 16, col: 26, offset: 197},
+								pos: position{line: 20, col: 26, offset: 346},
 								expr: &actionExpr{
-									pos: position{line: 16, col: 27, offset: 198},
+									pos: position{line: 20, col: 27, offset: 347},
 									run: (*parser).callonProfileSet7,
 									expr: &seqExpr{
-										pos: position{line: 16, col: 27, offset: 198},
+										pos: position{line: 20, col: 27, offset: 347},
 										exprs: []interface{}{
 											&labeledExpr{
-												pos:   position{line: 16, col: 27, offset: 198},
+												pos:   position{line: 20, col: 27, offset: 347},
+												label: "item",
+												expr: &ruleRefExpr{
+													pos:  position{line: 20, col: 32, offset: 352},
+													name: "Item",
+												},
+											},
+											&ruleRefExpr{
+												pos:  position{line: 20, col: 37, offset: 357},
+												name: "_",
+											},
+										},
+									},
+								},
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 22, col: 5, offset: 383},
+							val:        "}",
+							ignoreCase: false,
+							want:       "\"}\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Item",
+			pos:  position{line: 35, col: 1, offset: 612},
+			expr: &choiceExpr{
+				pos: position{line: 35, col: 8, offset: 619},
+				alternatives: []interface{}{
+					&ruleRefExpr{
+						pos:  position{line: 35, col: 8, offset: 619},
+						name: "Include",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 35, col: 18, offset: 629},
+						name: "Define",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 35, col: 27, offset: 638},
+						name: "Entry",
+					},
+				},
+			},
+		},
+		{
+			name: "Include",
+			pos:  position{line: 37, col: 1, offset: 645},
+			expr: &actionExpr{
+				pos: position{line: 37, col: 11, offset: 655},
+				run: (*parser).callonInclude1,
+				expr: &seqExpr{
+					pos: position{line: 37, col: 11, offset: 655},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 37, col: 11, offset: 655},
+							val:        "include",
+							ignoreCase: false,
+							want:       "\"include\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 37, col: 21, offset: 665},
+							name: "__",
+						},
+						&labeledExpr{
+							pos:   position{line: 37, col: 24, offset: 668},
+							label: "path",
+							expr: &ruleRefExpr{
+								pos:  position{line: 37, col: 29, offset: 673},
+								name: "String",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 37, col: 36, offset: 680},
+							name: "__",
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 37, col: 39, offset: 683},
+							expr: &litMatcher{
+								pos:        position{line: 37, col: 39, offset: 683},
+								val:        ",",
+								ignoreCase: false,
+								want:       "\",\"",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Define",
+			pos:  position{line: 41, col: 1, offset: 726},
+			expr: &actionExpr{
+				pos: position{line: 41, col: 10, offset: 735},
+				run: (*parser).callonDefine1,
+				expr: &seqExpr{
+					pos: position{line: 41, col: 10, offset: 735},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 41, col: 10, offset: 735},
+							label: "name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 41, col: 15, offset: 740},
+								name: "Ident",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 41, col: 21, offset: 746},
+							name: "__",
+						},
+						&litMatcher{
+							pos:        position{line: 41, col: 24, offset: 749},
+							val:        "=",
+							ignoreCase: false,
+							want:       "\"=\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 41, col: 28, offset: 753},
+							name: "__",
+						},
+						&labeledExpr{
+							pos:   position{line: 41, col: 31, offset: 756},
+							label: "value",
+							expr: &ruleRefExpr{
+								pos:  position{line: 41, col: 37, offset: 762},
+								name: "Expr",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 41, col: 42, offset: 767},
+							name: "__",
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 41, col: 45, offset: 770},
+							expr: &litMatcher{
+								pos:        position{line: 41, col: 45, offset: 770},
+								val:        ",",
+								ignoreCase: false,
+								want:       "\",\"",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Entry",
+			pos:  position{line: 45, col: 1, offset: 834},
+			expr: &actionExpr{
+				pos: position{line: 45, col: 9, offset: 842},
+				run: (*parser).callonEntry1,
+				expr: &seqExpr{
+					pos: position{line: 45, col: 9, offset: 842},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 45, col: 9, offset: 842},
+							label: "key",
+							expr: &ruleRefExpr{
+								pos:  position{line: 45, col: 13, offset: 846},
+								name: "Key",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 45, col: 17, offset: 850},
+							name: "__",
+						},
+						&litMatcher{
+							pos:        position{line: 45, col: 20, offset: 853},
+							val:        ":",
+							ignoreCase: false,
+							want:       "\":\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 45, col: 24, offset: 857},
+							name: "__",
+						},
+						&labeledExpr{
+							pos:   position{line: 45, col: 27, offset: 860},
+							label: "parent",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 45, col: 34, offset: 867},
+								expr: &ruleRefExpr{
+									pos:  position{line: 45, col: 34, offset: 867},
+									name: "Extends",
+								},
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 45, col: 43, offset: 876},
+							name: "__",
+						},
+						&labeledExpr{
+							pos:   position{line: 45, col: 46, offset: 879},
+							label: "value",
+							expr: &ruleRefExpr{
+								pos:  position{line: 45, col: 52, offset: 885},
+								name: "Profile",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 45, col: 60, offset: 893},
+							name: "__",
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 45, col: 63, offset: 896},
+							expr: &litMatcher{
+								pos:        position{line: 45, col: 63, offset: 896},
+								val:        ",",
+								ignoreCase: false,
+								want:       "\",\"",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Extends",
+			pos:  position{line: 56, col: 1, offset: 1037},
+			expr: &actionExpr{
+				pos: position{line: 56, col: 11, offset: 1047},
+				run: (*parser).callonExtends1,
+				expr: &seqExpr{
+					pos: position{line: 56, col: 11, offset: 1047},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 56, col: 11, offset: 1047},
+							val:        "extends",
+							ignoreCase: false,
+							want:       "\"extends\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 56, col: 21, offset: 1057},
+							name: "__",
+						},
+						&labeledExpr{
+							pos:   position{line: 56, col: 24, offset: 1060},
+							label: "parent",
+							expr: &ruleRefExpr{
+								pos:  position{line: 56, col: 31, offset: 1067},
+								name: "String",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Profile",
+			pos:  position{line: 60, col: 1, offset: 1099},
+			expr: &actionExpr{
+				pos: position{line: 60, col: 14, offset: 1112},
+				run: (*parser).callonProfile1,
+				expr: &seqExpr{
+					pos: position{line: 60, col: 14, offset: 1112},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 60, col: 14, offset: 1112},
+							val:        "{",
+							ignoreCase: false,
+							want:       "\"{\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 60, col: 18, offset: 1116},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 60, col: 20, offset: 1118},
+							label: "items",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 60, col: 26, offset: 1124},
+								expr: &actionExpr{
+									pos: position{line: 60, col: 27, offset: 1125},
+									run: (*parser).callonProfile7,
+									expr: &seqExpr{
+										pos: position{line: 60, col: 27, offset: 1125},
+										exprs: []interface{}{
+											&labeledExpr{
+												pos:   position{line: 60, col: 27, offset: 1125},
 												label: "key",
 												expr: &ruleRefExpr{
-													pos:  position{line: 16, col: 31, offset: 202},
-													name: "String",
+													pos:  position{line: 60, col: 31, offset: 1129},
+													name: "Key",
 												},
 											},
 											&ruleRefExpr{
-												pos:  position{line: 16, col: 38, offset: 209},
+												pos:  position{line: 60, col: 35, offset: 1133},
 												name: "__",
 											},
 											&litMatcher{
-												pos:        position{line: 16, col: 41, offset: 212},
+												pos:        position{line: 60, col: 38, offset: 1136},
 												val:        ":",
 												ignoreCase: false,
 												want:       "\":\"",
 											},
 											&ruleRefExpr{
-												pos:  position{line: 16, col: 45, offset: 216},
+												pos:  position{line: 60, col: 42, offset: 1140},
 												name: "__",
 											},
 											&labeledExpr{
-												pos:   position{line: 16, col: 48, offset: 219},
+												pos:   position{line: 60, col: 45, offset: 1143},
 												label: "value",
 												expr: &ruleRefExpr{
-													pos:  position{line: 16, col: 54, offset: 225},
-													name: "Profile",
+													pos:  position{line: 60, col: 51, offset: 1149},
+													name: "Value",
 												},
 											},
 											&ruleRefExpr{
-												pos:  position{line: 16, col: 62, offset: 233},
+												pos:  position{line: 60, col: 57, offset: 1155},
 												name: "__",
 											},
+											&zeroOrOneExpr{
+												pos: position{line: 60, col: 60, offset: 1158},
+												expr: &litMatcher{
+													pos:        position{line: 60, col: 60, offset: 1158},
+													val:        ",",
+													ignoreCase: false,
+													want:       "\",\"",
+												},
+											},
+											&ruleRefExpr{
+												pos:  position{line: 60, col: 65, offset: 1163},
+												name: "_",
+											},
+										},
+									},
+								},
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 65, col: 5, offset: 1267},
+							val:        "}",
+							ignoreCase: false,
+							want:       "\"}\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Key",
+			pos:  position{line: 76, col: 1, offset: 1462},
+			expr: &choiceExpr{
+				pos: position{line: 76, col: 7, offset: 1468},
+				alternatives: []interface{}{
+					&ruleRefExpr{
+						pos:  position{line: 76, col: 7, offset: 1468},
+						name: "String",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 76, col: 16, offset: 1477},
+						name: "Ident",
+					},
+				},
+			},
+		},
+		{
+			name: "Value",
+			pos:  position{line: 78, col: 1, offset: 1484},
+			expr: &choiceExpr{
+				pos: position{line: 78, col: 9, offset: 1492},
+				alternatives: []interface{}{
+					&ruleRefExpr{
+						pos:  position{line: 78, col: 9, offset: 1492},
+						name: "Normal",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 78, col: 18, offset: 1501},
+						name: "Uniform",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 78, col: 28, offset: 1511},
+						name: "LogNormal",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 78, col: 40, offset: 1523},
+						name: "Histogram",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 78, col: 52, offset: 1535},
+						name: "Constant",
+					},
+				},
+			},
+		},
+		{
+			name: "Normal",
+			pos:  position{line: 80, col: 1, offset: 1545},
+			expr: &actionExpr{
+				pos: position{line: 80, col: 10, offset: 1554},
+				run: (*parser).callonNormal1,
+				expr: &seqExpr{
+					pos: position{line: 80, col: 10, offset: 1554},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 80, col: 10, offset: 1554},
+							val:        "normal",
+							ignoreCase: false,
+							want:       "\"normal\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 80, col: 19, offset: 1563},
+							name: "__",
+						},
+						&litMatcher{
+							pos:        position{line: 80, col: 22, offset: 1566},
+							val:        "(",
+							ignoreCase: false,
+							want:       "\"(\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 80, col: 26, offset: 1570},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 80, col: 28, offset: 1572},
+							label: "mu",
+							expr: &ruleRefExpr{
+								pos:  position{line: 80, col: 31, offset: 1575},
+								name: "Expr",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 80, col: 36, offset: 1580},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 80, col: 38, offset: 1582},
+							val:        ",",
+							ignoreCase: false,
+							want:       "\",\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 80, col: 42, offset: 1586},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 80, col: 44, offset: 1588},
+							label: "sigma",
+							expr: &ruleRefExpr{
+								pos:  position{line: 80, col: 50, offset: 1594},
+								name: "Expr",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 80, col: 55, offset: 1599},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 80, col: 57, offset: 1601},
+							val:        ")",
+							ignoreCase: false,
+							want:       "\")\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Uniform",
+			pos:  position{line: 87, col: 1, offset: 1680},
+			expr: &actionExpr{
+				pos: position{line: 87, col: 11, offset: 1690},
+				run: (*parser).callonUniform1,
+				expr: &seqExpr{
+					pos: position{line: 87, col: 11, offset: 1690},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 87, col: 11, offset: 1690},
+							val:        "uniform",
+							ignoreCase: false,
+							want:       "\"uniform\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 87, col: 21, offset: 1700},
+							name: "__",
+						},
+						&litMatcher{
+							pos:        position{line: 87, col: 24, offset: 1703},
+							val:        "(",
+							ignoreCase: false,
+							want:       "\"(\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 87, col: 28, offset: 1707},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 87, col: 30, offset: 1709},
+							label: "min",
+							expr: &ruleRefExpr{
+								pos:  position{line: 87, col: 34, offset: 1713},
+								name: "Expr",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 87, col: 39, offset: 1718},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 87, col: 41, offset: 1720},
+							val:        ",",
+							ignoreCase: false,
+							want:       "\",\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 87, col: 45, offset: 1724},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 87, col: 47, offset: 1726},
+							label: "max",
+							expr: &ruleRefExpr{
+								pos:  position{line: 87, col: 51, offset: 1730},
+								name: "Expr",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 87, col: 56, offset: 1735},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 87, col: 58, offset: 1737},
+							val:        ")",
+							ignoreCase: false,
+							want:       "\")\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "LogNormal",
+			pos:  position{line: 94, col: 1, offset: 1815},
+			expr: &actionExpr{
+				pos: position{line: 94, col: 13, offset: 1827},
+				run: (*parser).callonLogNormal1,
+				expr: &seqExpr{
+					pos: position{line: 94, col: 13, offset: 1827},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 94, col: 13, offset: 1827},
+							val:        "lognormal",
+							ignoreCase: false,
+							want:       "\"lognormal\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 94, col: 25, offset: 1839},
+							name: "__",
+						},
+						&litMatcher{
+							pos:        position{line: 94, col: 28, offset: 1842},
+							val:        "(",
+							ignoreCase: false,
+							want:       "\"(\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 94, col: 32, offset: 1846},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 94, col: 34, offset: 1848},
+							label: "mu",
+							expr: &ruleRefExpr{
+								pos:  position{line: 94, col: 37, offset: 1851},
+								name: "Expr",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 94, col: 42, offset: 1856},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 94, col: 44, offset: 1858},
+							val:        ",",
+							ignoreCase: false,
+							want:       "\",\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 94, col: 48, offset: 1862},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 94, col: 50, offset: 1864},
+							label: "sigma",
+							expr: &ruleRefExpr{
+								pos:  position{line: 94, col: 56, offset: 1870},
+								name: "Expr",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 94, col: 61, offset: 1875},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 94, col: 63, offset: 1877},
+							val:        ")",
+							ignoreCase: false,
+							want:       "\")\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Histogram",
+			pos:  position{line: 101, col: 1, offset: 1959},
+			expr: &actionExpr{
+				pos: position{line: 101, col: 13, offset: 1971},
+				run: (*parser).callonHistogram1,
+				expr: &seqExpr{
+					pos: position{line: 101, col: 13, offset: 1971},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 101, col: 13, offset: 1971},
+							val:        "histogram",
+							ignoreCase: false,
+							want:       "\"histogram\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 101, col: 25, offset: 1983},
+							name: "__",
+						},
+						&litMatcher{
+							pos:        position{line: 101, col: 28, offset: 1986},
+							val:        "(",
+							ignoreCase: false,
+							want:       "\"(\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 101, col: 32, offset: 1990},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 101, col: 34, offset: 1992},
+							label: "first",
+							expr: &ruleRefExpr{
+								pos:  position{line: 101, col: 40, offset: 1998},
+								name: "Bin",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 101, col: 44, offset: 2002},
+							label: "rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 101, col: 49, offset: 2007},
+								expr: &actionExpr{
+									pos: position{line: 101, col: 50, offset: 2008},
+									run: (*parser).callonHistogram11,
+									expr: &seqExpr{
+										pos: position{line: 101, col: 50, offset: 2008},
+										exprs: []interface{}{
+											&ruleRefExpr{
+												pos:  position{line: 101, col: 50, offset: 2008},
+												name: "_",
+											},
 											&litMatcher{
-												pos:        position{line: 16, col: 65, offset: 236},
+												pos:        position{line: 101, col: 52, offset: 2010},
 												val:        ",",
 												ignoreCase: false,
 												want:       "\",\"",
 											},
 											&ruleRefExpr{
-												pos:  position{line: 16, col: 69, offset: 240},
+												pos:  position{line: 101, col: 56, offset: 2014},
 												name: "_",
 											},
+											&labeledExpr{
+												pos:   position{line: 101, col: 58, offset: 2016},
+												label: "bin",
+												expr: &ruleRefExpr{
+													pos:  position{line: 101, col: 62, offset: 2020},
+													name: "Bin",
+												},
+											},
 										},
 									},
 								},
 							},
 						},
-						&litMatcher{
-							pos:        position{line: 21, col: 5, offset: 334},
-							val:        "}",
-							ignoreCase: false,
-							want:       "\"}\"",
-						},
+						&ruleRefExpr{
+							pos:  position{line: 103, col: 5, offset: 2047},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 103, col: 7, offset: 2049},
+							val:        ")",
+							ignoreCase: false,
+							want:       "\")\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Bin",
+			pos:  position{line: 113, col: 1, offset: 2287},
+			expr: &actionExpr{
+				pos: position{line: 113, col: 7, offset: 2293},
+				run: (*parser).callonBin1,
+				expr: &seqExpr{
+					pos: position{line: 113, col: 7, offset: 2293},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 113, col: 7, offset: 2293},
+							label: "value",
+							expr: &ruleRefExpr{
+								pos:  position{line: 113, col: 13, offset: 2299},
+								name: "Expr",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 113, col: 18, offset: 2304},
+							name: "__",
+						},
+						&litMatcher{
+							pos:        position{line: 113, col: 21, offset: 2307},
+							val:        ":",
+							ignoreCase: false,
+							want:       "\":\"",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 113, col: 25, offset: 2311},
+							name: "__",
+						},
+						&labeledExpr{
+							pos:   position{line: 113, col: 28, offset: 2314},
+							label: "weight",
+							expr: &ruleRefExpr{
+								pos:  position{line: 113, col: 35, offset: 2321},
+								name: "Expr",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Constant",
+			pos:  position{line: 120, col: 1, offset: 2422},
+			expr: &actionExpr{
+				pos: position{line: 120, col: 12, offset: 2433},
+				run: (*parser).callonConstant1,
+				expr: &labeledExpr{
+					pos:   position{line: 120, col: 12, offset: 2433},
+					label: "value",
+					expr: &ruleRefExpr{
+						pos:  position{line: 120, col: 18, offset: 2439},
+						name: "Expr",
+					},
+				},
+			},
+		},
+		{
+			name: "Expr",
+			pos:  position{line: 124, col: 1, offset: 2488},
+			expr: &actionExpr{
+				pos: position{line: 124, col: 8, offset: 2495},
+				run: (*parser).callonExpr1,
+				expr: &seqExpr{
+					pos: position{line: 124, col: 8, offset: 2495},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 124, col: 8, offset: 2495},
+							label: "first",
+							expr: &ruleRefExpr{
+								pos:  position{line: 124, col: 14, offset: 2501},
+								name: "Term",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 124, col: 19, offset: 2506},
+							label: "rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 124, col: 24, offset: 2511},
+								expr: &actionExpr{
+									pos: position{line: 124, col: 25, offset: 2512},
+									run: (*parser).callonExpr7,
+									expr: &seqExpr{
+										pos: position{line: 124, col: 25, offset: 2512},
+										exprs: []interface{}{
+											&ruleRefExpr{
+												pos:  position{line: 124, col: 25, offset: 2512},
+												name: "__",
+											},
+											&labeledExpr{
+												pos:   position{line: 124, col: 28, offset: 2515},
+												label: "op",
+												expr: &charClassMatcher{
+													pos:        position{line: 124, col: 31, offset: 2518},
+													val:        "[+-]",
+													chars:      []rune{'+', '-'},
+													ignoreCase: false,
+													inverted:   false,
+												},
+											},
+											&ruleRefExpr{
+												pos:  position{line: 124, col: 36, offset: 2523},
+												name: "__",
+											},
+											&labeledExpr{
+												pos:   position{line: 124, col: 39, offset: 2526},
+												label: "term",
+												expr: &ruleRefExpr{
+													pos:  position{line: 124, col: 44, offset: 2531},
+													name: "Term",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Term",
+			pos:  position{line: 142, col: 1, offset: 2818},
+			expr: &actionExpr{
+				pos: position{line: 142, col: 8, offset: 2825},
+				run: (*parser).callonTerm1,
+				expr: &seqExpr{
+					pos: position{line: 142, col: 8, offset: 2825},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 142, col: 8, offset: 2825},
+							label: "first",
+							expr: &ruleRefExpr{
+								pos:  position{line: 142, col: 14, offset: 2831},
+								name: "Factor",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 142, col: 21, offset: 2838},
+							label: "rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 142, col: 26, offset: 2843},
+								expr: &actionExpr{
+									pos: position{line: 142, col: 27, offset: 2844},
+									run: (*parser).callonTerm7,
+									expr: &seqExpr{
+										pos: position{line: 142, col: 27, offset: 2844},
+										exprs: []interface{}{
+											&ruleRefExpr{
+												pos:  position{line: 142, col: 27, offset: 2844},
+												name: "__",
+											},
+											&labeledExpr{
+												pos:   position{line: 142, col: 30, offset: 2847},
+												label: "op",
+												expr: &charClassMatcher{
+													pos:        position{line: 142, col: 33, offset: 2850},
+													val:        "[*/]",
+													chars:      []rune{'*', '/'},
+													ignoreCase: false,
+													inverted:   false,
+												},
+											},
+											&ruleRefExpr{
+												pos:  position{line: 142, col: 38, offset: 2855},
+												name: "__",
+											},
+											&labeledExpr{
+												pos:   position{line: 142, col: 41, offset: 2858},
+												label: "factor",
+												expr: &ruleRefExpr{
+													pos:  position{line: 142, col: 48, offset: 2865},
+													name: "Factor",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Factor",
+			pos:  position{line: 160, col: 1, offset: 3156},
+			expr: &choiceExpr{
+				pos: position{line: 160, col: 10, offset: 3165},
+				alternatives: []interface{}{
+					&actionExpr{
+						pos: position{line: 160, col: 10, offset: 3165},
+						run: (*parser).callonFactor2,
+						expr: &seqExpr{
+							pos: position{line: 160, col: 10, offset: 3165},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 160, col: 10, offset: 3165},
+									val:        "(",
+									ignoreCase: false,
+									want:       "\"(\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 160, col: 14, offset: 3169},
+									name: "_",
+								},
+								&labeledExpr{
+									pos:   position{line: 160, col: 16, offset: 3171},
+									label: "x",
+									expr: &ruleRefExpr{
+										pos:  position{line: 160, col: 18, offset: 3173},
+										name: "Expr",
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 160, col: 23, offset: 3178},
+									name: "_",
+								},
+								&litMatcher{
+									pos:        position{line: 160, col: 25, offset: 3180},
+									val:        ")",
+									ignoreCase: false,
+									want:       "\")\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 162, col: 5, offset: 3205},
+						run: (*parser).callonFactor10,
+						expr: &seqExpr{
+							pos: position{line: 162, col: 5, offset: 3205},
+							exprs: []interface{}{
+								&litMatcher{
+									pos:        position{line: 162, col: 5, offset: 3205},
+									val:        "-",
+									ignoreCase: false,
+									want:       "\"-\"",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 162, col: 9, offset: 3209},
+									name: "__",
+								},
+								&labeledExpr{
+									pos:   position{line: 162, col: 12, offset: 3212},
+									label: "x",
+									expr: &ruleRefExpr{
+										pos:  position{line: 162, col: 14, offset: 3214},
+										name: "Factor",
+									},
+								},
+							},
+						},
+					},
+					&ruleRefExpr{
+						pos:  position{line: 164, col: 5, offset: 3253},
+						name: "Number",
+					},
+					&actionExpr{
+						pos: position{line: 164, col: 14, offset: 3262},
+						run: (*parser).callonFactor17,
+						expr: &labeledExpr{
+							pos:   position{line: 164, col: 14, offset: 3262},
+							label: "name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 164, col: 19, offset: 3267},
+								name: "Ident",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Number",
+			pos:  position{line: 168, col: 1, offset: 3310},
+			expr: &actionExpr{
+				pos: position{line: 168, col: 10, offset: 3319},
+				run: (*parser).callonNumber1,
+				expr: &seqExpr{
+					pos: position{line: 168, col: 10, offset: 3319},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 168, col: 10, offset: 3319},
+							label: "x",
+							expr: &ruleRefExpr{
+								pos:  position{line: 168, col: 12, offset: 3321},
+								name: "Float",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 168, col: 18, offset: 3327},
+							label: "unit",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 168, col: 23, offset: 3332},
+								expr: &ruleRefExpr{
+									pos:  position{line: 168, col: 23, offset: 3332},
+									name: "Unit",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Unit",
+			pos:  position{line: 175, col: 1, offset: 3426},
+			expr: &actionExpr{
+				pos: position{line: 175, col: 8, offset: 3433},
+				run: (*parser).callonUnit1,
+				expr: &seqExpr{
+					pos: position{line: 175, col: 8, offset: 3433},
+					exprs: []interface{}{
+						&choiceExpr{
+							pos: position{line: 175, col: 9, offset: 3434},
+							alternatives: []interface{}{
+								&litMatcher{
+									pos:        position{line: 175, col: 9, offset: 3434},
+									val:        "ps",
+									ignoreCase: false,
+									want:       "\"ps\"",
+								},
+								&litMatcher{
+									pos:        position{line: 175, col: 16, offset: 3441},
+									val:        "ns",
+									ignoreCase: false,
+									want:       "\"ns\"",
+								},
+								&litMatcher{
+									pos:        position{line: 175, col: 23, offset: 3448},
+									val:        "fJ",
+									ignoreCase: false,
+									want:       "\"fJ\"",
+								},
+								&litMatcher{
+									pos:        position{line: 175, col: 30, offset: 3455},
+									val:        "pJ",
+									ignoreCase: false,
+									want:       "\"pJ\"",
+								},
+							},
+						},
+						&notExpr{
+							pos: position{line: 175, col: 36, offset: 3461},
+							expr: &charClassMatcher{
+								pos:        position{line: 175, col: 37, offset: 3462},
+								val:        "[a-zA-Z0-9_]",
+								chars:      []rune{'_'},
+								ranges:     []rune{'a', 'z', 'A', 'Z', '0', '9'},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
 					},
 				},
 			},
 		},
 		{
-			name: "Profile",
-			pos:  position{line: 32, col: 1, offset: 524},
+			name: "Ident",
+			pos:  position{line: 179, col: 1, offset: 3508},
 			expr: &actionExpr{
-				pos: position{line: 32, col: 14, offset: 537},
-				run: (*parser).callonProfile1,
+				pos: position{line: 179, col: 9, offset: 3516},
+				run: (*parser).callonIdent1,
 				expr: &seqExpr{
-					pos: position{line: 32, col: 14, offset: 537},
+					pos: position{line: 179, col: 9, offset: 3516},
 					exprs: []interface{}{
-						&litMatcher{
-							pos:        position{line: 32, col: 14, offset: 537},
-							val:        "{",
+						&charClassMatcher{
+							pos:        position{line: 179, col: 9, offset: 3516},
+							val:        "[a-zA-Z_]",
+							chars:      []rune{'_'},
+							ranges:     []rune{'a', 'z', 'A', 'Z'},
 							ignoreCase: false,
-							want:       "\"{\"",
+							inverted:   false,
 						},
-						&ruleRefExpr{
-							pos:  position{line: 32, col: 18, offset: 541},
-							name: "_",
-						},
-						&labeledExpr{
-							pos:   position{line: 32, col: 20, offset: 543},
-							label: "items",
-							expr: &zeroOrMoreExpr{
-								pos: position{line: 32, col: 26, offset: 549},
-								expr: &actionExpr{
-									pos: position{line: 32, col: 27, offset: 550},
-									run: (*parser).callonProfile7,
-									expr: &seqExpr{
-										pos: position{line: 32, col: 27, offset: 550},
-										exprs: []interface{}{
-											&labeledExpr{
-												pos:   position{line: 32, col: 27, offset: 550},
-												label: "key",
-												expr: &ruleRefExpr{
-													pos:  position{line: 32, col: 31, offset: 554},
-													name: "String",
-												},
-											},
-											&ruleRefExpr{
-												pos:  position{line: 32, col: 38, offset: 561},
-												name: "__",
-											},
-											&litMatcher{
-												pos:        position{line: 32, col: 41, offset: 564},
-												val:        ":",
-												ignoreCase: false,
-												want:       "\":\"",
-											},
-											&ruleRefExpr{
-												pos:  position{line: 32, col: 45, offset: 568},
-												name: "__",
-											},
-											&labeledExpr{
-												pos:   position{line: 32, col: 48, offset: 571},
-												label: "value",
-												expr: &ruleRefExpr{
-													pos:  position{line: 32, col: 54, offset: 577},
-													name: "Float",
-												},
-											},
-											&ruleRefExpr{
-												pos:  position{line: 32, col: 60, offset: 583},
-												name: "__",
-											},
-											&litMatcher{
-												pos:        position{line: 32, col: 63, offset: 586},
-												val:        ",",
-												ignoreCase: false,
-												want:       "\",\"",
-											},
-											&ruleRefExpr{
-												pos:  position{line: 32, col: 67, offset: 590},
-												name: "_",
-											},
-										},
-									},
-								},
+						&zeroOrMoreExpr{
+							pos: position{line: 179, col: 19, offset: 3526},
+							expr: &charClassMatcher{
+								pos:        position{line: 179, col: 19, offset: 3526},
+								val:        "[a-zA-Z0-9_]",
+								chars:      []rune{'_'},
+								ranges:     []rune{'a', 'z', 'A', 'Z', '0', '9'},
+								ignoreCase: false,
+								inverted:   false,
 							},
 						},
-						&litMatcher{
-							pos:        position{line: 37, col: 5, offset: 684},
-							val:        "}",
-							ignoreCase: false,
-							want:       "\"}\"",
-						},
 					},
 				},
 			},
 		},
 		{
 			name: "String",
-			pos:  position{line: 48, col: 1, offset: 869},
+			pos:  position{line: 183, col: 1, offset: 3573},
 			expr: &actionExpr{
-				pos: position{line: 48, col: 10, offset: 878},
+				pos: position{line: 183, col: 10, offset: 3582},
 				run: (*parser).callonString1,
 				expr: &seqExpr{
-					pos: position{line: 48, col: 10, offset: 878},
+					pos: position{line: 183, col: 10, offset: 3582},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 48, col: 10, offset: 878},
+							pos:        position{line: 183, col: 10, offset: 3582},
 							val:        "\"",
 							ignoreCase: false,
 							want:       "\"\\\"\"",
 						},
 						&zeroOrMoreExpr{
-							pos: position{line: 48, col: 15, offset: 883},
+							pos: position{line: 183, col: 15, offset: 3587},
 							expr: &charClassMatcher{
-								pos:        position{line: 48, col: 15, offset: 883},
+								pos:        position{line: 183, col: 15, offset: 3587},
 								val:        "[^\"]",
 								chars:      []rune{'"'},
 								ignoreCase: false,
@@ -262,7 +1203,7 @@ var g = &grammar{
 							},
 						},
 						&litMatcher{
-							pos:        position{line: 48, col: 21, offset: 889},
+							pos:        position{line: 183, col: 21, offset: 3593},
 							val:        "\"",
 							ignoreCase: false,
 							want:       "\"\\\"\"",
@@ -273,17 +1214,17 @@ var g = &grammar{
 		},
 		{
 			name: "Float",
-			pos:  position{line: 52, col: 1, offset: 944},
+			pos:  position{line: 187, col: 1, offset: 3648},
 			expr: &actionExpr{
-				pos: position{line: 52, col: 9, offset: 952},
+				pos: position{line: 187, col: 9, offset: 3656},
 				run: (*parser).callonFloat1,
 				expr: &seqExpr{
-					pos: position{line: 52, col: 9, offset: 952},
+					pos: position{line: 187, col: 9, offset: 3656},
 					exprs: []interface{}{
 						&zeroOrOneExpr{
-							pos: position{line: 52, col: 9, offset: 952},
+							pos: position{line: 187, col: 9, offset: 3656},
 							expr: &charClassMatcher{
-								pos:        position{line: 52, col: 9, offset: 952},
+								pos:        position{line: 187, col: 9, offset: 3656},
 								val:        "[+-]",
 								chars:      []rune{'+', '-'},
 								ignoreCase: false,
@@ -291,14 +1232,14 @@ var g = &grammar{
 							},
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 52, col: 15, offset: 958},
+							pos: position{line: 187, col: 15, offset: 3662},
 							expr: &seqExpr{
-								pos: position{line: 52, col: 16, offset: 959},
+								pos: position{line: 187, col: 16, offset: 3663},
 								exprs: []interface{}{
 									&zeroOrMoreExpr{
-										pos: position{line: 52, col: 16, offset: 959},
+										pos: position{line: 187, col: 16, offset: 3663},
 										expr: &charClassMatcher{
-											pos:        position{line: 52, col: 16, offset: 959},
+											pos:        position{line: 187, col: 16, offset: 3663},
 											val:        "[0-9]",
 											ranges:     []rune{'0', '9'},
 											ignoreCase: false,
@@ -306,7 +1247,7 @@ var g = &grammar{
 										},
 									},
 									&litMatcher{
-										pos:        position{line: 52, col: 23, offset: 966},
+										pos:        position{line: 187, col: 23, offset: 3670},
 										val:        ".",
 										ignoreCase: false,
 										want:       "\".\"",
@@ -315,15 +1256,86 @@ var g = &grammar{
 							},
 						},
 						&oneOrMoreExpr{
-							pos: position{line: 52, col: 29, offset: 972},
+							pos: position{line: 187, col: 29, offset: 3676},
 							expr: &charClassMatcher{
-								pos:        position{line: 52, col: 29, offset: 972},
+								pos:        position{line: 187, col: 29, offset: 3676},
 								val:        "[0-9]",
 								ranges:     []rune{'0', '9'},
 								ignoreCase: false,
 								inverted:   false,
 							},
 						},
+						&zeroOrOneExpr{
+							pos: position{line: 187, col: 36, offset: 3683},
+							expr: &seqExpr{
+								pos: position{line: 187, col: 37, offset: 3684},
+								exprs: []interface{}{
+									&charClassMatcher{
+										pos:        position{line: 187, col: 37, offset: 3684},
+										val:        "[eE]",
+										chars:      []rune{'e', 'E'},
+										ignoreCase: false,
+										inverted:   false,
+									},
+									&zeroOrOneExpr{
+										pos: position{line: 187, col: 42, offset: 3689},
+										expr: &charClassMatcher{
+											pos:        position{line: 187, col: 42, offset: 3689},
+											val:        "[+-]",
+											chars:      []rune{'+', '-'},
+											ignoreCase: false,
+											inverted:   false,
+										},
+									},
+									&oneOrMoreExpr{
+										pos: position{line: 187, col: 48, offset: 3695},
+										expr: &charClassMatcher{
+											pos:        position{line: 187, col: 48, offset: 3695},
+											val:        "[0-9]",
+											ranges:     []rune{'0', '9'},
+											ignoreCase: false,
+											inverted:   false,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Comment",
+			pos:  position{line: 191, col: 1, offset: 3756},
+			expr: &seqExpr{
+				pos: position{line: 191, col: 11, offset: 3766},
+				exprs: []interface{}{
+					&choiceExpr{
+						pos: position{line: 191, col: 12, offset: 3767},
+						alternatives: []interface{}{
+							&litMatcher{
+								pos:        position{line: 191, col: 12, offset: 3767},
+								val:        "#",
+								ignoreCase: false,
+								want:       "\"#\"",
+							},
+							&litMatcher{
+								pos:        position{line: 191, col: 18, offset: 3773},
+								val:        "//",
+								ignoreCase: false,
+								want:       "\"//\"",
+							},
+						},
+					},
+					&zeroOrMoreExpr{
+						pos: position{line: 191, col: 24, offset: 3779},
+						expr: &charClassMatcher{
+							pos:        position{line: 191, col: 24, offset: 3779},
+							val:        "[^\\n]",
+							chars:      []rune{'\n'},
+							ignoreCase: false,
+							inverted:   true,
+						},
 					},
 				},
 			},
@@ -331,14 +1343,14 @@ var g = &grammar{
 		{
 			name:        "__",
 			displayName: "\"nonewline\"",
-			pos:         position{line: 56, col: 1, offset: 1031},
+			pos:         position{line: 193, col: 1, offset: 3787},
 			expr: &actionExpr{
-				pos: position{line: 56, col: 18, offset: 1048},
+				pos: position{line: 193, col: 18, offset: 3804},
 				run: (*parser).callon__1,
 				expr: &zeroOrMoreExpr{
-					pos: position{line: 56, col: 18, offset: 1048},
+					pos: position{line: 193, col: 18, offset: 3804},
 					expr: &charClassMatcher{
-						pos:        position{line: 56, col: 18, offset: 1048},
+						pos:        position{line: 193, col: 18, offset: 3804},
 						val:        "[ \\t]",
 						chars:      []rune{' ', '\t'},
 						ignoreCase: false,
@@ -350,32 +1362,41 @@ var g = &grammar{
 		{
 			name:        "_",
 			displayName: "\"whitespace\"",
-			pos:         position{line: 60, col: 1, offset: 1077},
+			pos:         position{line: 197, col: 1, offset: 3833},
 			expr: &actionExpr{
-				pos: position{line: 60, col: 18, offset: 1094},
+				pos: position{line: 197, col: 18, offset: 3850},
 				run: (*parser).callon_1,
 				expr: &zeroOrMoreExpr{
-					pos: position{line: 60, col: 18, offset: 1094},
-					expr: &charClassMatcher{
-						pos:        position{line: 60, col: 18, offset: 1094},
-						val:        "[ \\n\\t\\r]",
-						chars:      []rune{' ', '\n', '\t', '\r'},
-						ignoreCase: false,
-						inverted:   false,
+					pos: position{line: 197, col: 18, offset: 3850},
+					expr: &choiceExpr{
+						pos: position{line: 197, col: 19, offset: 3851},
+						alternatives: []interface{}{
+							&charClassMatcher{
+								pos:        position{line: 197, col: 19, offset: 3851},
+								val:        "[ \\n\\t\\r]",
+								chars:      []rune{' ', '\n', '\t', '\r'},
+								ignoreCase: false,
+								inverted:   false,
+							},
+							&ruleRefExpr{
+								pos:  position{line: 197, col: 31, offset: 3863},
+								name: "Comment",
+							},
+						},
 					},
 				},
 			},
 		},
 		{
 			name: "EOF",
-			pos:  position{line: 64, col: 1, offset: 1127},
+			pos:  position{line: 201, col: 1, offset: 3895},
 			expr: &actionExpr{
-				pos: position{line: 64, col: 7, offset: 1133},
+				pos: position{line: 201, col: 7, offset: 3901},
 				run: (*parser).callonEOF1,
 				expr: &notExpr{
-					pos: position{line: 64, col: 7, offset: 1133},
+					pos: position{line: 201, col: 7, offset: 3901},
 					expr: &anyMatcher{
-						line: 64, col: 8, offset: 1134,
+						line: 201, col: 8, offset: 3902,
 					},
 				},
 			},
@@ -383,38 +1404,41 @@ var g = &grammar{
 	},
 }
 
-func (c *current) onTop1(pset interface{}) (interface{}, error) {
-	return pset.(ProfileSet), nil
+func (c *current) onTop1(entries interface{}) (interface{}, error) {
+	// included files are resolved along with the file that includes them
+	if c.globalStore["included"] == true {
+		return entries, nil
+	}
+	return newProfileSet(entries.([]entry))
 }
 
 func (p *parser) callonTop1() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onTop1(stack["pset"])
+	return p.cur.onTop1(stack["entries"])
 }
 
-func (c *current) onProfileSet7(key, value interface{}) (interface{}, error) {
-	return pair[string, Profile]{
-		key:   key.(string),
-		value: value.(Profile),
-	}, nil
+func (c *current) onProfileSet7(item interface{}) (interface{}, error) {
+	return item, nil
 }
 
 func (p *parser) callonProfileSet7() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onProfileSet7(stack["key"], stack["value"])
+	return p.cur.onProfileSet7(stack["item"])
 }
 
 func (c *current) onProfileSet1(items interface{}) (interface{}, error) {
-	m := make(map[string]Profile)
+	var entries []entry
 	for _, item := range items.([]interface{}) {
-		p := item.(pair[string, Profile])
-		m[p.key] = p.value
+		switch v := item.(type) {
+		case entry:
+			entries = append(entries, v)
+		case []entry:
+			entries = append(entries, v...)
+		}
 	}
-	return &profileSet{
-		profiles: m,
-	}, nil
+	return entries, nil
 }
 
 func (p *parser) callonProfileSet1() (interface{}, error) {
@@ -423,10 +1447,57 @@ func (p *parser) callonProfileSet1() (interface{}, error) {
 	return p.cur.onProfileSet1(stack["items"])
 }
 
+func (c *current) onInclude1(path interface{}) (interface{}, error) {
+	return c.include(path.(string))
+}
+
+func (p *parser) callonInclude1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onInclude1(stack["path"])
+}
+
+func (c *current) onDefine1(name, value interface{}) (interface{}, error) {
+	return nil, c.define(name.(string), value.(float64))
+}
+
+func (p *parser) callonDefine1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDefine1(stack["name"], stack["value"])
+}
+
+func (c *current) onEntry1(key, parent, value interface{}) (interface{}, error) {
+	e := entry{
+		key:   key.(string),
+		value: value.(*profile),
+	}
+	if parent != nil {
+		e.parent = parent.(string)
+	}
+	return e, nil
+}
+
+func (p *parser) callonEntry1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onEntry1(stack["key"], stack["parent"], stack["value"])
+}
+
+func (c *current) onExtends1(parent interface{}) (interface{}, error) {
+	return parent, nil
+}
+
+func (p *parser) callonExtends1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onExtends1(stack["parent"])
+}
+
 func (c *current) onProfile7(key, value interface{}) (interface{}, error) {
-	return pair[string, float64]{
+	return pair[string, Distribution]{
 		key:   key.(string),
-		value: value.(float64),
+		value: value.(Distribution),
 	}, nil
 }
 
@@ -437,9 +1508,9 @@ func (p *parser) callonProfile7() (interface{}, error) {
 }
 
 func (c *current) onProfile1(items interface{}) (interface{}, error) {
-	m := make(map[string]float64)
+	m := make(map[string]Distribution)
 	for _, item := range items.([]interface{}) {
-		p := item.(pair[string, float64])
+		p := item.(pair[string, Distribution])
 		m[p.key] = p.value
 	}
 	return &profile{
@@ -453,6 +1524,221 @@ func (p *parser) callonProfile1() (interface{}, error) {
 	return p.cur.onProfile1(stack["items"])
 }
 
+func (c *current) onNormal1(mu, sigma interface{}) (interface{}, error) {
+	return Normal{
+		Mu:    mu.(float64),
+		Sigma: sigma.(float64),
+	}, nil
+}
+
+func (p *parser) callonNormal1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onNormal1(stack["mu"], stack["sigma"])
+}
+
+func (c *current) onUniform1(min, max interface{}) (interface{}, error) {
+	return Uniform{
+		Min: min.(float64),
+		Max: max.(float64),
+	}, nil
+}
+
+func (p *parser) callonUniform1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onUniform1(stack["min"], stack["max"])
+}
+
+func (c *current) onLogNormal1(mu, sigma interface{}) (interface{}, error) {
+	return LogNormal{
+		Mu:    mu.(float64),
+		Sigma: sigma.(float64),
+	}, nil
+}
+
+func (p *parser) callonLogNormal1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onLogNormal1(stack["mu"], stack["sigma"])
+}
+
+func (c *current) onHistogram11(bin interface{}) (interface{}, error) {
+	return bin, nil
+}
+
+func (p *parser) callonHistogram11() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onHistogram11(stack["bin"])
+}
+
+func (c *current) onHistogram1(first, rest interface{}) (interface{}, error) {
+	h := Histogram{}
+	for _, item := range append([]interface{}{first}, rest.([]interface{})...) {
+		b := item.(pair[float64, float64])
+		h.Values = append(h.Values, b.key)
+		h.Weights = append(h.Weights, b.value)
+	}
+	return h, nil
+}
+
+func (p *parser) callonHistogram1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onHistogram1(stack["first"], stack["rest"])
+}
+
+func (c *current) onBin1(value, weight interface{}) (interface{}, error) {
+	return pair[float64, float64]{
+		key:   value.(float64),
+		value: weight.(float64),
+	}, nil
+}
+
+func (p *parser) callonBin1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onBin1(stack["value"], stack["weight"])
+}
+
+func (c *current) onConstant1(value interface{}) (interface{}, error) {
+	return Constant(value.(float64)), nil
+}
+
+func (p *parser) callonConstant1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onConstant1(stack["value"])
+}
+
+func (c *current) onExpr7(op, term interface{}) (interface{}, error) {
+	return pair[byte, float64]{
+		key:   op.([]byte)[0],
+		value: term.(float64),
+	}, nil
+}
+
+func (p *parser) callonExpr7() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onExpr7(stack["op"], stack["term"])
+}
+
+func (c *current) onExpr1(first, rest interface{}) (interface{}, error) {
+	x := first.(float64)
+	for _, item := range rest.([]interface{}) {
+		p := item.(pair[byte, float64])
+		if p.key == '+' {
+			x += p.value
+		} else {
+			x -= p.value
+		}
+	}
+	return x, nil
+}
+
+func (p *parser) callonExpr1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onExpr1(stack["first"], stack["rest"])
+}
+
+func (c *current) onTerm7(op, factor interface{}) (interface{}, error) {
+	return pair[byte, float64]{
+		key:   op.([]byte)[0],
+		value: factor.(float64),
+	}, nil
+}
+
+func (p *parser) callonTerm7() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onTerm7(stack["op"], stack["factor"])
+}
+
+func (c *current) onTerm1(first, rest interface{}) (interface{}, error) {
+	x := first.(float64)
+	for _, item := range rest.([]interface{}) {
+		p := item.(pair[byte, float64])
+		if p.key == '*' {
+			x *= p.value
+		} else {
+			x /= p.value
+		}
+	}
+	return x, nil
+}
+
+func (p *parser) callonTerm1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onTerm1(stack["first"], stack["rest"])
+}
+
+func (c *current) onFactor2(x interface{}) (interface{}, error) {
+	return x, nil
+}
+
+func (p *parser) callonFactor2() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onFactor2(stack["x"])
+}
+
+func (c *current) onFactor10(x interface{}) (interface{}, error) {
+	return -x.(float64), nil
+}
+
+func (p *parser) callonFactor10() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onFactor10(stack["x"])
+}
+
+func (c *current) onFactor17(name interface{}) (interface{}, error) {
+	return c.lookup(name.(string))
+}
+
+func (p *parser) callonFactor17() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onFactor17(stack["name"])
+}
+
+func (c *current) onNumber1(x, unit interface{}) (interface{}, error) {
+	if unit == nil {
+		return x, nil
+	}
+	return x.(float64) * units[unit.(string)], nil
+}
+
+func (p *parser) callonNumber1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onNumber1(stack["x"], stack["unit"])
+}
+
+func (c *current) onUnit1() (interface{}, error) {
+	return string(c.text), nil
+}
+
+func (p *parser) callonUnit1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onUnit1()
+}
+
+func (c *current) onIdent1() (interface{}, error) {
+	return string(c.text), nil
+}
+
+func (p *parser) callonIdent1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIdent1()
+}
+
 func (c *current) onString1() (interface{}, error) {
 	return string(c.text[1 : len(c.text)-1]), nil
 }
@@ -1,37 +1,337 @@
 package timing
 
 import (
+	"fmt"
+	"math/rand"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 )
 
 type Profile interface {
+	// the value of a key, or the mean of its distribution, 0.0 if missing
 	Find(name string) float64
+	// a new value drawn from the distribution of a key, 0.0 if missing
+	Sample(name string) float64
+	// the distribution of a key, nil if missing
+	Dist(name string) Distribution
+	// every key, including the ones inherited from the profiles it extends,
+	// in sorted order
+	Keys() []string
 }
 
 type profile struct {
-	values map[string]float64
+	values map[string]Distribution
+	// keys missing from values are looked up here, nil if this profile
+	// doesn't extend another one
+	parent *profile
 }
 
 func NewProfile() Profile {
 	return &profile{
-		values: make(map[string]float64),
+		values: make(map[string]Distribution),
 	}
 }
 
 func (p *profile) Find(name string) float64 {
-	delay, ok := p.values[name]
-	if ok {
-		return delay
+	d := p.Dist(name)
+	if d != nil {
+		return d.Mean()
 	}
 	return 0.0
 }
 
+// Sample draws from a source shared by every profile without one, use
+// WithRand for a reproducible stream
+func (p *profile) Sample(name string) float64 {
+	return sample(p, name, nil)
+}
+
+func (p *profile) Dist(name string) Distribution {
+	for ; p != nil; p = p.parent {
+		d, ok := p.values[name]
+		if ok {
+			return d
+		}
+	}
+	return nil
+}
+
+func (p *profile) Keys() []string {
+	seen := map[string]bool{}
+	var result []string
+	for q := p; q != nil; q = q.parent {
+		for key := range q.values {
+			if !seen[key] {
+				seen[key] = true
+				result = append(result, key)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// lockedSource lets profiles without their own source share one
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+var (
+	sharedOnce sync.Once
+	shared     *rand.Rand
+)
+
+// sharedRand is seeded from math/rand the first time a profile without a
+// source draws a sample
+func sharedRand() *rand.Rand {
+	sharedOnce.Do(func() {
+		shared = rand.New(&lockedSource{
+			src: rand.NewSource(rand.Int63()).(rand.Source64),
+		})
+	})
+	return shared
+}
+
+func sample(p Profile, name string, r *rand.Rand) float64 {
+	d := p.Dist(name)
+	if d == nil {
+		return 0.0
+	}
+	if _, ok := d.(Constant); !ok && r == nil {
+		r = sharedRand()
+	}
+	return d.Sample(r)
+}
+
+type randProfile struct {
+	Profile
+	r *rand.Rand
+}
+
+// WithRand returns p drawing its samples from r
+func WithRand(p Profile, r *rand.Rand) Profile {
+	if rp, ok := p.(*randProfile); ok {
+		p = rp.Profile
+	}
+	return &randProfile{
+		Profile: p,
+		r:       r,
+	}
+}
+
+func (p *randProfile) Sample(name string) float64 {
+	return sample(p.Profile, name, p.r)
+}
+
+type prefixProfile struct {
+	Profile
+	prefix string
+	keys   map[string]bool
+}
+
+// WithPrefix returns p reading the key prefix.<name> in place of name
+// wherever p has it, so that one profile can time each port of a process
+// separately and fall back to the shared key for the others
+func WithPrefix(p Profile, prefix string) Profile {
+	keys := make(map[string]bool)
+	for _, key := range p.Keys() {
+		if strings.HasPrefix(key, prefix+".") {
+			keys[key] = true
+		}
+	}
+	return &prefixProfile{
+		Profile: p,
+		prefix:  prefix,
+		keys:    keys,
+	}
+}
+
+func (p *prefixProfile) key(name string) string {
+	if key := p.prefix + "." + name; p.keys[key] {
+		return key
+	}
+	return name
+}
+
+func (p *prefixProfile) Find(name string) float64 {
+	return p.Profile.Find(p.key(name))
+}
+
+func (p *prefixProfile) Sample(name string) float64 {
+	return p.Profile.Sample(p.key(name))
+}
+
+func (p *prefixProfile) Dist(name string) Distribution {
+	return p.Profile.Dist(p.key(name))
+}
+
+type watchProfile struct {
+	Profile
+	fn func(name string, ok bool)
+}
+
+// Watch returns p calling fn with every key that is read and whether p has
+// it. fn may be called from several goroutines when p is shared.
+func Watch(p Profile, fn func(name string, ok bool)) Profile {
+	return &watchProfile{
+		Profile: p,
+		fn:      fn,
+	}
+}
+
+func (p *watchProfile) Find(name string) float64 {
+	p.Dist(name)
+	return p.Profile.Find(name)
+}
+
+func (p *watchProfile) Sample(name string) float64 {
+	p.Dist(name)
+	return p.Profile.Sample(name)
+}
+
+func (p *watchProfile) Dist(name string) Distribution {
+	d := p.Profile.Dist(name)
+	p.fn(name, d != nil)
+	return d
+}
+
+// Default is the key of the profile used when nothing else matches
+const Default = "default"
+
 type ProfileSet interface {
+	// the profile stored under exactly this key, nil if missing
 	Find(name string) Profile
+	// Match returns the profile of a process, the key it is stored under
+	// and the index of the name that matched, or nil if nothing matches.
+	// The names are tried in order, the process name and then the function
+	// that implements it. For each name an exact key wins, then the first
+	// pattern in the file that matches. The "default" profile is used when
+	// none of the names match, with an index of -1.
+	//
+	// A pattern is a key containing * or ?, where * matches any characters
+	// except '.', ** matches any characters and ? matches one character
+	// except '.', or a regular expression between slashes. Patterns must
+	// match the whole name.
+	Match(names ...string) (Profile, string, int)
+	// every key in the order of the files
+	Keys() []string
+	// the key of the profile that key extends, "" if it doesn't
+	Extends(key string) string
+}
+
+type pattern struct {
+	key string
+	re  *regexp.Regexp
 }
 
 type profileSet struct {
 	profiles map[string]Profile
+	keys     []string
+	// the keys of profiles that are patterns, in the order of the file
+	patterns []pattern
+	// the key of the profile each one extends
+	parents map[string]string
+}
+
+// entry is a profile as written in a file, before the profiles it extends
+// have been resolved
+type entry struct {
+	key    string
+	parent string
+	value  *profile
+}
+
+// compilePattern returns nil if key is a plain name
+func compilePattern(key string) (*regexp.Regexp, error) {
+	if len(key) > 1 && strings.HasPrefix(key, "/") && strings.HasSuffix(key, "/") {
+		return regexp.Compile("^(?:" + key[1:len(key)-1] + ")$")
+	}
+	if !strings.ContainsAny(key, "*?") {
+		return nil, nil
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(key); i++ {
+		switch {
+		case strings.HasPrefix(key[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case key[i] == '*':
+			expr.WriteString("[^.]*")
+		case key[i] == '?':
+			expr.WriteString("[^.]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(key[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// newProfileSet links each profile to the one it extends
+func newProfileSet(entries []entry) (*profileSet, error) {
+	s := &profileSet{
+		profiles: make(map[string]Profile),
+		parents:  make(map[string]string),
+	}
+	values := make(map[string]*profile)
+	for _, e := range entries {
+		if _, ok := values[e.key]; ok {
+			return nil, fmt.Errorf("profile '%s' is defined twice", e.key)
+		}
+		values[e.key] = e.value
+		s.profiles[e.key] = e.value
+		s.keys = append(s.keys, e.key)
+
+		re, err := compilePattern(e.key)
+		if err != nil {
+			return nil, fmt.Errorf("profile '%s': %w", e.key, err)
+		}
+		if re != nil {
+			s.patterns = append(s.patterns, pattern{e.key, re})
+		}
+	}
+
+	for _, e := range entries {
+		if e.parent == "" {
+			continue
+		}
+		parent, ok := values[e.parent]
+		if !ok {
+			return nil, fmt.Errorf("profile '%s' extends '%s', which isn't defined", e.key, e.parent)
+		}
+		for p := parent; p != nil; p = p.parent {
+			if p == e.value {
+				return nil, fmt.Errorf("profile '%s' extends itself", e.key)
+			}
+		}
+		e.value.parent = parent
+		s.parents[e.key] = e.parent
+	}
+	return s, nil
 }
 
 func NewProfileSet() ProfileSet {
@@ -41,18 +341,11 @@ func NewProfileSet() ProfileSet {
 }
 
 func LoadProfileSet(path string) (ProfileSet, error) {
-	file, err := os.Open(path)
+	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	p, err := ParseReader(path, file)
-	if err != nil {
-		return nil, err
-	}
-
-	return p.(ProfileSet), nil
+	return ParseProfileSet(path, b)
 }
 
 func (s *profileSet) Find(name string) Profile {
@@ -62,3 +355,28 @@ func (s *profileSet) Find(name string) Profile {
 	}
 	return nil
 }
+
+func (s *profileSet) Match(names ...string) (Profile, string, int) {
+	for i, name := range names {
+		if p, ok := s.profiles[name]; ok {
+			return p, name, i
+		}
+		for _, pat := range s.patterns {
+			if pat.re.MatchString(name) {
+				return s.profiles[pat.key], pat.key, i
+			}
+		}
+	}
+	if p, ok := s.profiles[Default]; ok {
+		return p, Default, -1
+	}
+	return nil, "", -1
+}
+
+func (s *profileSet) Keys() []string {
+	return s.keys
+}
+
+func (s *profileSet) Extends(key string) string {
+	return s.parents[key]
+}
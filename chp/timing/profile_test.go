@@ -0,0 +1,421 @@
+package timing
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitParseDistributions(t *testing.T) {
+	s, err := Parse("test.prof", []byte(`{
+	"dut": {
+		"a": 0.5,
+		"b": normal(0.23, 0.02),
+		"c": uniform(0.1, 0.3),
+		"d": lognormal(-1.5, 0.1),
+		"e": histogram(9.0: 1, 10.0: 2, 11.0: 1),
+	},
+}`))
+	assert.NoError(t, err)
+	p := s.(ProfileSet).Find("dut")
+	assert.NotNil(t, p)
+
+	assert.Equal(t, Constant(0.5), p.Dist("a"))
+	assert.Equal(t, Normal{0.23, 0.02}, p.Dist("b"))
+	assert.Equal(t, Uniform{0.1, 0.3}, p.Dist("c"))
+	assert.Equal(t, LogNormal{-1.5, 0.1}, p.Dist("d"))
+	assert.Equal(t, Histogram{[]float64{9, 10, 11}, []float64{1, 2, 1}}, p.Dist("e"))
+	assert.Nil(t, p.Dist("f"))
+
+	assert.Equal(t, 0.5, p.Find("a"))
+	assert.Equal(t, 0.23, p.Find("b"))
+	assert.InDelta(t, 0.2, p.Find("c"), 1e-9)
+	assert.InDelta(t, 0.2242, p.Find("d"), 1e-4)
+	assert.Equal(t, 10.0, p.Find("e"))
+	assert.Equal(t, 0.0, p.Find("f"))
+	assert.Equal(t, 0.0, p.Sample("f"))
+}
+
+func TestUnitSample(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	dists := []Distribution{
+		Constant(0.5),
+		Normal{0.23, 0.02},
+		Uniform{0.1, 0.3},
+		LogNormal{-1.5, 0.1},
+		Histogram{[]float64{9, 10, 11}, []float64{1, 2, 1}},
+	}
+	for _, d := range dists {
+		sum := 0.0
+		for i := 0; i < 10000; i++ {
+			sum += d.Sample(r)
+		}
+		assert.InDelta(t, d.Mean(), sum/10000, d.Mean()*0.01, "%#v", d)
+	}
+
+	for i := 0; i < 1000; i++ {
+		x := Uniform{0.1, 0.3}.Sample(r)
+		assert.True(t, x >= 0.1 && x < 0.3)
+		assert.Contains(t, []float64{9, 10, 11}, dists[4].Sample(r))
+	}
+}
+
+func TestUnitSampleClamped(t *testing.T) {
+	// a third of the samples are clamped to zero
+	d := Normal{0.05, 0.1}
+	r := rand.New(rand.NewSource(1))
+	sum := 0.0
+	for i := 0; i < 100000; i++ {
+		x := d.Sample(r)
+		assert.GreaterOrEqual(t, x, 0.0)
+		sum += x
+	}
+	assert.InDelta(t, 0.0698, d.Mean(), 1e-4)
+	assert.InDelta(t, d.Mean(), sum/100000, d.Mean()*0.01)
+
+	p := &profile{
+		values: map[string]Distribution{
+			"d0": d,
+		},
+	}
+	assert.Equal(t, d.Mean(), p.Find("d0"))
+	assert.Equal(t, 0.0, Normal{-0.1, 0}.Mean())
+}
+
+func TestUnitWithRand(t *testing.T) {
+	p := &profile{
+		values: map[string]Distribution{
+			"c": Constant(0.5),
+			"n": Normal{0.23, 0.02},
+		},
+	}
+
+	a := WithRand(p, rand.New(rand.NewSource(7)))
+	b := WithRand(p, rand.New(rand.NewSource(7)))
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, a.Sample("n"), b.Sample("n"))
+		assert.Equal(t, 0.5, a.Sample("c"))
+	}
+	assert.Equal(t, 0.23, a.Find("n"))
+
+	// constants never draw from the source
+	r := rand.New(rand.NewSource(7))
+	c := WithRand(p, r)
+	c.Sample("c")
+	assert.Equal(t, rand.New(rand.NewSource(7)).Int63(), r.Int63())
+}
+
+func TestUnitSampleShared(t *testing.T) {
+	p := &profile{
+		values: map[string]Distribution{
+			"n": Normal{0.23, 0.02},
+		},
+	}
+
+	// profiles without a source share one instead of creating their own
+	p.Sample("n")
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		p.Sample("n")
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				p.Sample("n")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestUnitMatch(t *testing.T) {
+	s, err := Parse("test.prof", []byte(`{
+	"top.pipe.*.buf": {
+		"d0": 1.0,
+	},
+	"top.pipe.**": {
+		"d0": 2.0,
+	},
+	"/top\.alu[0-9]+/": {
+		"d0": 3.0,
+	},
+	"top.pipe.0.buf": {
+		"d0": 4.0,
+	},
+	"**.Buffer[...]": {
+		"d0": 5.0,
+	},
+	"default": {
+		"d0": 6.0,
+	},
+}`))
+	assert.NoError(t, err)
+	set := s.(ProfileSet)
+
+	match := func(names ...string) string {
+		_, key, _ := set.Match(names...)
+		return key
+	}
+
+	// an exact key wins over patterns
+	assert.Equal(t, "top.pipe.0.buf", match("top.pipe.0.buf", "chp.Buffer[...]"))
+	// patterns are tried in the order of the file
+	assert.Equal(t, "top.pipe.*.buf", match("top.pipe.1.buf", "chp.Buffer[...]"))
+	assert.Equal(t, "top.pipe.**", match("top.pipe.1.x.buf", "chp.Buffer[...]"))
+	assert.Equal(t, "/top\\.alu[0-9]+/", match("top.alu12"))
+	// patterns must match the whole name
+	assert.Equal(t, "default", match("top.alu12.x"))
+	// the process name wins over the function
+	assert.Equal(t, "**.Buffer[...]", match("top.buf", "git.broccolimicro.io/Broccoli/pr.git/chp.Buffer[...]"))
+	assert.Equal(t, "default", match("top.buf", "git.broccolimicro.io/Broccoli/pr.git/chp.Copy[...]"))
+
+	p, _, i := set.Match("top.pipe.1.buf")
+	assert.Equal(t, 1.0, p.Find("d0"))
+	assert.Equal(t, 0, i)
+	_, _, i = set.Match("top.buf", "chp.Buffer[...]")
+	assert.Equal(t, 1, i)
+	_, _, i = set.Match("top.buf")
+	assert.Equal(t, -1, i)
+	assert.Equal(t, []string{"top.pipe.*.buf", "top.pipe.**", "/top\\.alu[0-9]+/", "top.pipe.0.buf", "**.Buffer[...]", "default"}, set.Keys())
+
+	p, key, _ := NewProfileSet().Match("top.buf")
+	assert.Nil(t, p)
+	assert.Equal(t, "", key)
+}
+
+func TestUnitExtends(t *testing.T) {
+	s, err := Parse("test.prof", []byte(`{
+	"default": {
+		"d0": 0.2,
+		"e0": 10.0,
+	},
+	"top.fast": extends "default" {
+		"d0": 0.1,
+	},
+	"top.fast.low": extends "top.fast" {
+		"e0": 5.0,
+	},
+}`))
+	assert.NoError(t, err)
+	p := s.(ProfileSet).Find("top.fast.low")
+	assert.Equal(t, 0.1, p.Find("d0"))
+	assert.Equal(t, 5.0, p.Find("e0"))
+	assert.Equal(t, 10.0, s.(ProfileSet).Find("top.fast").Find("e0"))
+	assert.Equal(t, 0.2, s.(ProfileSet).Find("default").Find("d0"))
+	assert.Equal(t, "top.fast", s.(ProfileSet).Extends("top.fast.low"))
+	assert.Equal(t, "", s.(ProfileSet).Extends("default"))
+
+	_, err = Parse("test.prof", []byte(`{
+	"a": extends "b" {
+	},
+}`))
+	assert.ErrorContains(t, err, "profile 'a' extends 'b', which isn't defined")
+
+	_, err = Parse("test.prof", []byte(`{
+	"a": extends "b" {
+	},
+	"b": extends "a" {
+	},
+}`))
+	assert.ErrorContains(t, err, "extends itself")
+
+	_, err = Parse("test.prof", []byte(`{
+	"a": {
+	},
+	"a": {
+	},
+}`))
+	assert.ErrorContains(t, err, "profile 'a' is defined twice")
+}
+
+func TestUnitWatch(t *testing.T) {
+	p := &profile{
+		values: map[string]Distribution{
+			"d0": Constant(0.5),
+		},
+	}
+
+	var missing []string
+	w := Watch(WithRand(p, rand.New(rand.NewSource(1))), func(name string, ok bool) {
+		if !ok {
+			missing = append(missing, name)
+		}
+	})
+	assert.Equal(t, 0.5, w.Find("d0"))
+	assert.Equal(t, 0.5, w.Sample("d0"))
+	assert.Equal(t, 0.0, w.Find("e0"))
+	assert.Equal(t, 0.0, w.Sample("d0R"))
+	assert.Equal(t, []string{"e0", "d0R"}, missing)
+}
+
+func TestUnitWithPrefix(t *testing.T) {
+	p := &profile{
+		values: map[string]Distribution{
+			"d0":       Constant(0.5),
+			"d0R":      Constant(0.2),
+			"in.1.d0":  Constant(0.7),
+			"in.10.e0": Constant(3),
+		},
+	}
+
+	var read []string
+	w := Watch(p, func(name string, ok bool) {
+		read = append(read, name)
+	})
+	in1 := WithRand(WithPrefix(w, "in.1"), rand.New(rand.NewSource(1)))
+	assert.Equal(t, 0.7, in1.Sample("d0"))
+	assert.Equal(t, 0.7, in1.Find("d0"))
+	assert.Equal(t, 0.2, in1.Sample("d0R"))
+	assert.Equal(t, 0.0, in1.Sample("e0"))
+	assert.Equal(t, Constant(0.7), in1.Dist("d0"))
+
+	in0 := WithPrefix(w, "in.0")
+	assert.Equal(t, 0.5, in0.Sample("d0"))
+	assert.Equal(t, []string{"in.1.d0", "in.1.d0", "d0R", "e0", "in.1.d0", "d0"}, read)
+}
+
+func TestUnitParseExpressions(t *testing.T) {
+	s, err := Parse("test.prof", []byte(`{
+	# gate delays from characterization
+	tinv = 20ps
+	twire = 0.005 // per wire
+	ebit = 0.5pJ
+
+	dut: {
+		d0: 2*tinv + twire
+		d0R: (tinv - twire) / 2, # trailing comma is optional
+		e0: 8*ebit + 3fJ
+		dm: -tinv*-2
+		dx: 1.5e-2ns
+		n: normal(tinv, tinv/10)
+	}
+}`))
+	assert.NoError(t, err)
+	p := s.(ProfileSet).Find("dut")
+	assert.InDelta(t, 0.045, p.Find("d0"), 1e-12)
+	assert.InDelta(t, 0.0075, p.Find("d0R"), 1e-12)
+	assert.InDelta(t, 4003.0, p.Find("e0"), 1e-9)
+	assert.InDelta(t, 0.04, p.Find("dm"), 1e-12)
+	assert.InDelta(t, 0.015, p.Find("dx"), 1e-12)
+	assert.InDelta(t, 0.002, p.Dist("n").(Normal).Sigma, 1e-12)
+}
+
+func TestUnitParseInclude(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "cells"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "cells", "gates.prof"), []byte(`{
+	tinv = 0.02
+	"gate": {
+		"d0": tinv,
+		"e0": 1.0,
+	},
+}`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "top.prof"), []byte(`{
+	include "cells/gates.prof"
+	"dut": extends "gate" {
+		"d0R": 3*tinv,
+	},
+}`), 0644))
+
+	s, err := LoadProfileSet(filepath.Join(dir, "top.prof"))
+	assert.NoError(t, err)
+	p := s.Find("dut")
+	assert.Equal(t, 0.02, p.Find("d0"))
+	assert.InDelta(t, 0.06, p.Find("d0R"), 1e-12)
+	assert.Equal(t, 1.0, p.Find("e0"))
+
+	// b and c both include the gates shared by every component
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "cells", "b.prof"), []byte(`{
+	include "gates.prof"
+	"b": extends "gate" {
+		"d0R": 2*tinv,
+	},
+}`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "cells", "c.prof"), []byte(`{
+	include "gates.prof"
+	"c": extends "gate" {
+		"d0R": 4*tinv,
+	},
+}`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "diamond.prof"), []byte(`{
+	include "cells/b.prof"
+	include "cells/c.prof"
+	include "cells/gates.prof"
+}`), 0644))
+	s, err = LoadProfileSet(filepath.Join(dir, "diamond.prof"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gate", "b", "c"}, s.Keys())
+	assert.InDelta(t, 0.04, s.Find("b").Find("d0R"), 1e-12)
+	assert.InDelta(t, 0.08, s.Find("c").Find("d0R"), 1e-12)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "loop.prof"), []byte(`{
+	include "loop.prof"
+}`), 0644))
+	_, err = LoadProfileSet(filepath.Join(dir, "loop.prof"))
+	assert.ErrorContains(t, err, "loop.prof' includes itself")
+}
+
+func TestUnitParseErrors(t *testing.T) {
+	_, err := Parse("test.prof", []byte(`{
+	"dut": {
+		"d0": 2*tinv,
+	},
+}`))
+	assert.ErrorContains(t, err, "test.prof:3:11")
+	assert.ErrorContains(t, err, "undefined constant 'tinv'")
+
+	_, err = Parse("test.prof", []byte(`{
+	"dut": {
+		"d0": 0.1 0.2,
+	},
+}`))
+	assert.ErrorContains(t, err, "test.prof:3:13")
+
+	_, err = Parse("test.prof", []byte(`{
+	x = 1
+	x = 2
+}`))
+	assert.ErrorContains(t, err, "test.prof:3:2")
+	assert.ErrorContains(t, err, "constant 'x' is defined twice")
+}
+
+func TestUnitFormat(t *testing.T) {
+	dists := []Distribution{
+		Constant(0.5),
+		Constant(1.5e-05),
+		Normal{0.23, 0.02},
+		Uniform{0.1, 0.3},
+		LogNormal{-1.5, 0.1},
+		Histogram{[]float64{9, 10, 11}, []float64{1, 2, 1}},
+	}
+	for _, d := range dists {
+		s, err := Parse("test.prof", []byte(`{"dut": {"x": `+d.String()+`}}`))
+		assert.NoError(t, err, d.String())
+		assert.Equal(t, d, s.(ProfileSet).Find("dut").Dist("x"))
+	}
+	assert.Equal(t, "histogram(9: 1, 10: 2, 11: 1)", dists[5].String())
+}
+
+func TestUnitProfileKeys(t *testing.T) {
+	s, err := Parse("test.prof", []byte(`{
+	"default": {
+		"e0": 10.0,
+		"d0": 0.2,
+	},
+	"top.fast": extends "default" {
+		"d0": 0.1,
+		"d0R": 0.1,
+	},
+}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"d0", "d0R", "e0"}, s.(ProfileSet).Find("top.fast").Keys())
+	assert.Equal(t, []string{"d0", "e0"}, s.(ProfileSet).Find("default").Keys())
+	assert.Empty(t, NewProfile().Keys())
+}
@@ -16,6 +16,11 @@ type Value[vtype interface{}] struct {
 	V vtype
 }
 
+// Wait, if set, is called with the underlying channel before blocking on
+// an Action or Signal. The deterministic kernel in chp uses it to run
+// whatever will fill the channel.
+var Wait func(c interface{})
+
 type Action[T interface{}] chan Value[T]
 
 func (p Action[T]) C() chan Value[T] {
@@ -23,6 +28,9 @@ func (p Action[T]) C() chan Value[T] {
 }
 
 func (p Action[T]) Recv() (T, float64) {
+	if Wait != nil {
+		Wait(p.C())
+	}
 	a, ok := <-p
 	if !ok {
 		panic(Deadlock)
@@ -37,6 +45,9 @@ func (p Signal) C() chan float64 {
 }
 
 func (p Signal) Send() float64 {
+	if Wait != nil {
+		Wait(p.C())
+	}
 	a, ok := <-p
 	if !ok {
 		panic(Deadlock)
@@ -0,0 +1,173 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// formats accepted by Create
+const (
+	JSON   = "json"
+	Binary = "binary"
+)
+
+// file names used for each format within a run directory
+const (
+	JSONFile   = "trace.jsonl"
+	BinaryFile = "trace.bin"
+)
+
+type Writer interface {
+	Write(e Event) error
+	Close() error
+}
+
+type encoder interface {
+	Encode(e interface{}) error
+}
+
+// writer serializes events from every process into a single stream
+type writer struct {
+	mu   sync.Mutex
+	file io.Closer
+	buf  *bufio.Writer
+	enc  encoder
+}
+
+// NewJSON writes one JSON object per line
+func NewJSON(w io.WriteCloser) Writer {
+	buf := bufio.NewWriter(w)
+	return &writer{
+		file: w,
+		buf:  buf,
+		enc:  json.NewEncoder(buf),
+	}
+}
+
+// NewBinary writes a gob stream of events
+func NewBinary(w io.WriteCloser) Writer {
+	buf := bufio.NewWriter(w)
+	return &writer{
+		file: w,
+		buf:  buf,
+		enc:  gob.NewEncoder(buf),
+	}
+}
+
+// Create opens a trace in the run directory in the given format
+func Create(dir, format string) (Writer, error) {
+	var name string
+	switch format {
+	case JSON:
+		name = JSONFile
+	case Binary:
+		name = BinaryFile
+	default:
+		return nil, fmt.Errorf("unrecognized trace format '%s', expected '%s' or '%s'", format, JSON, Binary)
+	}
+
+	fptr, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	if format == JSON {
+		return NewJSON(fptr), nil
+	}
+	return NewBinary(fptr), nil
+}
+
+func (w *writer) Write(e Event) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.enc == nil {
+		return fmt.Errorf("trace already closed")
+	}
+	return w.enc.Encode(e)
+}
+
+func (w *writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.enc == nil {
+		return nil
+	}
+	w.enc = nil
+	err := w.buf.Flush()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type Reader interface {
+	// Read returns io.EOF after the last event
+	Read() (Event, error)
+}
+
+type decoder interface {
+	Decode(e interface{}) error
+}
+
+type reader struct {
+	dec decoder
+}
+
+func NewJSONReader(r io.Reader) Reader {
+	return &reader{json.NewDecoder(r)}
+}
+
+func NewBinaryReader(r io.Reader) Reader {
+	return &reader{gob.NewDecoder(r)}
+}
+
+func (r *reader) Read() (Event, error) {
+	var e Event
+	err := r.dec.Decode(&e)
+	return e, err
+}
+
+// Find returns the path of the trace in a run directory or "" if the
+// simulation didn't write one
+func Find(dir string) string {
+	for _, name := range []string{JSONFile, BinaryFile} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads every event from a trace file, the format is chosen by its
+// name
+func Load(path string) ([]Event, error) {
+	fptr, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fptr.Close()
+
+	var r Reader
+	if filepath.Ext(path) == filepath.Ext(BinaryFile) {
+		r = NewBinaryReader(bufio.NewReader(fptr))
+	} else {
+		r = NewJSONReader(bufio.NewReader(fptr))
+	}
+
+	var events []Event
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		events = append(events, e)
+	}
+}
@@ -0,0 +1,193 @@
+// Package trace is a structured alternative to the tab separated channel and
+// cycle logs written by chp. Every token that passes through a named channel
+// and every cycle reported through Globals.Cycle is recorded as an Event.
+package trace
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type Direction string
+
+const (
+	Send  Direction = "send"
+	Recv  Direction = "recv"
+	Cycle Direction = "cycle"
+)
+
+type Kind string
+
+const (
+	Bool   Kind = "bool"
+	Int    Kind = "int"
+	Uint   Kind = "uint"
+	Float  Kind = "float"
+	String Kind = "string"
+	List   Kind = "list"
+	Struct Kind = "struct"
+	// anything else is kept as its %v representation
+	Other Kind = "other"
+)
+
+// Value is a typed encoding of a token. Scalars are stored as text so that
+// no precision is lost, lists use Elems and structs use Fields.
+type Value struct {
+	Kind   Kind    `json:"kind"`
+	Text   string  `json:"text,omitempty"`
+	Elems  []Value `json:"elems,omitempty"`
+	Fields []Field `json:"fields,omitempty"`
+}
+
+type Field struct {
+	Name  string `json:"name"`
+	Value Value  `json:"value"`
+}
+
+type Event struct {
+	Process string    `json:"process"`
+	Channel string    `json:"channel,omitempty"`
+	Dir     Direction `json:"dir"`
+	// when the operation was requested and when it completed in ns
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	// energy in fJ, only for cycles
+	Energy float64 `json:"energy,omitempty"`
+	// type description, the same as the header of a channel log
+	Type  string `json:"type,omitempty"`
+	Value *Value `json:"value,omitempty"`
+}
+
+// TypeString describes a type the way chp's channel logs do, for example
+// "{C:bool D:[int64]}"
+func TypeString(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		return "[" + TypeString(t.Elem()) + "]"
+	} else if t.Kind() == reflect.Struct {
+		var b strings.Builder
+		b.WriteString("{")
+		for i := 0; i < t.NumField(); i++ {
+			if i != 0 {
+				b.WriteString(" ")
+			}
+			f := t.Field(i)
+			b.WriteString(f.Name + ":" + TypeString(f.Type))
+		}
+		b.WriteString("}")
+		return b.String()
+	}
+	return t.Name()
+}
+
+// Encode converts a token into a Value
+func Encode(value interface{}) Value {
+	return encode(reflect.ValueOf(value))
+}
+
+func encode(v reflect.Value) Value {
+	if !v.IsValid() {
+		return Value{Kind: Other, Text: "<nil>"}
+	}
+
+	if v.CanInterface() {
+		if b, ok := v.Interface().(*big.Int); ok && b != nil {
+			return Value{Kind: Int, Text: b.String()}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return Value{Kind: Bool, Text: strconv.FormatBool(v.Bool())}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Value{Kind: Int, Text: strconv.FormatInt(v.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Value{Kind: Uint, Text: strconv.FormatUint(v.Uint(), 10)}
+	case reflect.Float32, reflect.Float64:
+		return Value{Kind: Float, Text: strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())}
+	case reflect.String:
+		return Value{Kind: String, Text: v.String()}
+	case reflect.Slice, reflect.Array:
+		result := Value{Kind: List, Elems: make([]Value, v.Len())}
+		for i := 0; i < v.Len(); i++ {
+			result.Elems[i] = encode(v.Index(i))
+		}
+		return result
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return Value{Kind: Other, Text: "<nil>"}
+		}
+		return encode(v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		result := Value{Kind: Struct, Fields: make([]Field, v.NumField())}
+		for i := 0; i < v.NumField(); i++ {
+			result.Fields[i] = Field{t.Field(i).Name, encode(v.Field(i))}
+		}
+		return result
+	}
+
+	if v.CanInterface() {
+		return Value{Kind: Other, Text: fmt.Sprintf("%v", v.Interface())}
+	}
+	return Value{Kind: Other, Text: v.String()}
+}
+
+// String formats the value the same way as %v would have formatted the
+// original token
+func (v Value) String() string {
+	switch v.Kind {
+	case List:
+		elems := make([]string, len(v.Elems))
+		for i, e := range v.Elems {
+			elems[i] = e.String()
+		}
+		return "[" + strings.Join(elems, " ") + "]"
+	case Struct:
+		fields := make([]string, len(v.Fields))
+		for i, f := range v.Fields {
+			fields[i] = f.Value.String()
+		}
+		return "{" + strings.Join(fields, " ") + "}"
+	}
+	return v.Text
+}
+
+func (v Value) Int() (int64, error) {
+	return strconv.ParseInt(v.Text, 10, 64)
+}
+
+func (v Value) Uint() (uint64, error) {
+	return strconv.ParseUint(v.Text, 10, 64)
+}
+
+func (v Value) Float() (float64, error) {
+	return strconv.ParseFloat(v.Text, 64)
+}
+
+func (v Value) Bool() (bool, error) {
+	return strconv.ParseBool(v.Text)
+}
+
+func (v Value) BigInt() (*big.Int, error) {
+	b, ok := new(big.Int).SetString(v.Text, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer '%s'", v.Text)
+	}
+	return b, nil
+}
+
+// Field returns the named field of a struct
+func (v Value) Field(name string) (Value, bool) {
+	for _, f := range v.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return Value{}, false
+}
@@ -0,0 +1,80 @@
+package trace
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type token struct {
+	C bool
+	D []int64
+	E *big.Int
+}
+
+type closer struct {
+	*bytes.Buffer
+}
+
+func (c closer) Close() error {
+	return nil
+}
+
+func TestEncode(t *testing.T) {
+	value := token{true, []int64{1, -2}, big.NewInt(12345678901)}
+	assert.Equal(t, "{C:bool D:[int64] E:}", TypeString(reflect.TypeOf(value)))
+
+	v := Encode(value)
+	assert.Equal(t, Struct, v.Kind)
+	assert.Equal(t, "{true [1 -2] 12345678901}", v.String())
+
+	d, ok := v.Field("D")
+	assert.True(t, ok)
+	i, err := d.Elems[1].Int()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-2), i)
+
+	e, _ := v.Field("E")
+	b, err := e.BigInt()
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678901", b.String())
+
+	assert.Equal(t, "0.1", Encode(0.1).Text)
+}
+
+func TestRoundTrip(t *testing.T) {
+	v := Encode(token{false, []int64{3}, big.NewInt(1)})
+	events := []Event{
+		{Process: "top.dut", Channel: "L", Dir: Recv, Start: 0, End: 0.5, Type: "{C:bool D:[int64] E:}", Value: &v},
+		{Process: "top.dut", Dir: Cycle, Start: 0.5, End: 1, Energy: 10},
+	}
+
+	for _, format := range []string{JSON, Binary} {
+		buf := closer{&bytes.Buffer{}}
+		var w Writer
+		var r func(io.Reader) Reader
+		if format == JSON {
+			w, r = NewJSON(buf), NewJSONReader
+		} else {
+			w, r = NewBinary(buf), NewBinaryReader
+		}
+		for _, e := range events {
+			assert.NoError(t, w.Write(e))
+		}
+		assert.NoError(t, w.Close())
+		assert.Error(t, w.Write(events[0]))
+
+		reader := r(buf)
+		for _, e := range events {
+			result, err := reader.Read()
+			assert.NoError(t, err)
+			assert.Equal(t, e, result)
+		}
+		_, err := reader.Read()
+		assert.Equal(t, io.EOF, err)
+	}
+}
@@ -0,0 +1,107 @@
+package chp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/timing"
+)
+
+// profileUsage writes which profile each process matched, the keys it read
+// and the profiles nobody used, followed by a diagnosis of processes that
+// are likely missing their timing
+func (m *monitor) profileUsage(w io.Writer, t timing.ProfileSet) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var procs []*globals
+	for _, g := range m.processes {
+		if g.init {
+			procs = append(procs, g)
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool {
+		return procs[i].name < procs[j].name
+	})
+
+	used := map[string]bool{}
+	fmt.Fprintf(w, "processes\n")
+	for _, g := range procs {
+		if g.profileKey == "" {
+			fmt.Fprintf(w, "\t%s\tnone\n", g.name)
+		} else {
+			fmt.Fprintf(w, "\t%s\t%s\t%s\n", g.name, g.profileKey, g.profileBy)
+			// the profiles it extends are used through it
+			for key := g.profileKey; key != "" && !used[key]; key = t.Extends(key) {
+				used[key] = true
+			}
+		}
+	}
+
+	reads := make(map[*globals]map[string]bool, len(procs))
+	for _, g := range procs {
+		g.readsMu.Lock()
+		reads[g] = make(map[string]bool, len(g.reads))
+		for key, ok := range g.reads {
+			reads[g][key] = ok
+		}
+		g.readsMu.Unlock()
+	}
+
+	fmt.Fprintf(w, "\nkeys\n")
+	for _, g := range procs {
+		for _, key := range sortedKeys(reads[g]) {
+			if reads[g][key] {
+				fmt.Fprintf(w, "\t%s\t%s\t%g\n", g.name, key, g.profile.Find(key))
+			} else {
+				fmt.Fprintf(w, "\t%s\t%s\tmissing\n", g.name, key)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\nunused profiles\n")
+	if t != nil {
+		for _, key := range t.Keys() {
+			if !used[key] {
+				fmt.Fprintf(w, "\t%s\n", key)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\ndiagnosis\n")
+	for _, g := range procs {
+		if len(reads[g]) == 0 {
+			continue
+		}
+		if g.profileKey == "" {
+			fmt.Fprintf(w, "\t%s has no profile, every key it reads is 0.0\n", g.name)
+			continue
+		}
+
+		var missing []string
+		zero := true
+		for _, key := range sortedKeys(reads[g]) {
+			if !reads[g][key] {
+				missing = append(missing, key)
+			} else if g.profile.Find(key) != 0 {
+				zero = false
+			}
+		}
+		if zero {
+			fmt.Fprintf(w, "\t%s runs with all-zero timing\n", g.name)
+		} else if len(missing) > 0 {
+			fmt.Fprintf(w, "\t%s reads %s, missing from profile %s\n", g.name, strings.Join(missing, ", "), g.profileKey)
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,69 @@
+package chp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/param"
+)
+
+func TestIntegrationProfileUsage(t *testing.T) {
+	out := param.String(2, "test/chp/profile_usage")
+
+	assert.NoError(t, os.MkdirAll(out, 0755))
+	profile := filepath.Join(out, "usage.prof")
+	assert.NoError(t, os.WriteFile(profile, []byte(`{
+	"base": {
+		"e0": 1.0,
+	},
+	"top.buf": extends "base" {
+		"d0": 0.1,
+	},
+	"**.SinkN[...]": {
+		"d0": 0.0,
+	},
+	"unused": {
+	},
+}`), 0644))
+
+	g, err := New(out, profile)
+	assert.NoError(t, err)
+	g.SetDeterministic(true)
+
+	Ls, Lr := Chan[int]("L", 0)
+	Rs, Rr := Chan[int]("R", 0)
+
+	go SourceN(10, Values(1, 2, 3), g.Sub("src"), Ls)
+	go Buffer(g.Sub("buf"), Lr, Rs)
+	go SinkN(10, g.Sub("sink"), Rr)
+	g.Done()
+
+	report, err := os.ReadFile(filepath.Join(out, "profile-usage"))
+	assert.NoError(t, err)
+	assert.Equal(t, `processes
+	top.buf	top.buf	by name
+	top.sink	**.SinkN[...]	by function
+	top.src	none
+
+keys
+	top.buf	d0	0.1
+	top.buf	d0L	missing
+	top.buf	d0R	missing
+	top.buf	e0	1
+	top.sink	d0	0
+	top.sink	e0	missing
+	top.src	d0	missing
+	top.src	e0	missing
+
+unused profiles
+	unused
+
+diagnosis
+	top.buf reads d0L, d0R, missing from profile top.buf
+	top.sink runs with all-zero timing
+	top.src has no profile, every key it reads is 0.0
+`, string(report))
+}
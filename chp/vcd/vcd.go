@@ -0,0 +1,447 @@
+// Package vcd records channel handshakes and data as a value change dump
+// that can be viewed in GTKWave. Changes are buffered as they are reported
+// by each process and written in time order when the writer is closed. At
+// most maxBuffered changes are held in memory, the rest are sorted and
+// spilled into temporary files next to the dump until then.
+package vcd
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	Wire    = "wire"
+	Integer = "integer"
+	Real    = "real"
+	String  = "string"
+)
+
+type variable struct {
+	path  []string
+	kind  string
+	width int
+	id    string
+	index int
+	// the current value of a toggled wire
+	level bool
+}
+
+type change struct {
+	time int64
+	seq  int
+	v    *variable
+	// empty for a toggle
+	value  string
+	toggle bool
+}
+
+func (c change) before(o change) bool {
+	if c.time != o.time {
+		return c.time < o.time
+	}
+	return c.seq < o.seq
+}
+
+// record is a change as it is stored in a spilled file
+type record struct {
+	Time   int64
+	Seq    int
+	Var    int
+	Value  string
+	Toggle bool
+}
+
+// maxBuffered is the number of changes held in memory before they are
+// spilled
+const maxBuffered = 1 << 16
+
+type Writer struct {
+	mu      sync.Mutex
+	path    string
+	vars    map[string]*variable
+	order   []*variable
+	changes []change
+	seq     int
+	limit   int
+	// sorted runs of changes, merged by Close
+	runs []*os.File
+	err  error
+}
+
+// Create checks that the dump can be written to path. Nothing is written
+// until Close.
+func Create(path string) (*Writer, error) {
+	fptr, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	fptr.Close()
+
+	return &Writer{
+		path:  path,
+		vars:  map[string]*variable{},
+		limit: maxBuffered,
+	}, nil
+}
+
+// Scope splits a dotted name, like those created by Globals.Sub, into its
+// scopes
+func Scope(names ...string) []string {
+	var result []string
+	for _, name := range names {
+		if name != "" {
+			result = append(result, strings.Split(name, ".")...)
+		}
+	}
+	return result
+}
+
+// ident encodes the index of a variable as a short identifier using the
+// printable ASCII characters
+func ident(i int) string {
+	var b []byte
+	for {
+		b = append(b, byte('!'+i%94))
+		i /= 94
+		if i == 0 {
+			break
+		}
+	}
+	return string(b)
+}
+
+// picoseconds converts the simulated time in ns
+func picoseconds(t float64) int64 {
+	return int64(math.Round(t * 1000))
+}
+
+func (w *Writer) variable(path []string, kind string, width int) *variable {
+	key := strings.Join(path, "\x00")
+	v, ok := w.vars[key]
+	if !ok {
+		v = &variable{
+			path:  append([]string{}, path...),
+			kind:  kind,
+			width: width,
+			id:    ident(len(w.order)),
+			index: len(w.order),
+		}
+		w.vars[key] = v
+		w.order = append(w.order, v)
+	}
+	return v
+}
+
+func (w *Writer) add(path []string, kind string, width int, t float64, value string, toggle bool) {
+	v := w.variable(path, kind, width)
+	w.changes = append(w.changes, change{
+		time:   picoseconds(t),
+		seq:    w.seq,
+		v:      v,
+		value:  value,
+		toggle: toggle,
+	})
+	w.seq++
+	if len(w.changes) >= w.limit && w.err == nil {
+		w.err = w.spill()
+	}
+}
+
+func (w *Writer) sort() {
+	sort.Slice(w.changes, func(i, j int) bool {
+		return w.changes[i].before(w.changes[j])
+	})
+}
+
+// spill sorts the buffered changes and writes them into a new run
+func (w *Writer) spill() error {
+	w.sort()
+	fptr, err := os.CreateTemp(filepath.Dir(w.path), "."+filepath.Base(w.path)+".*")
+	if err != nil {
+		return err
+	}
+	w.runs = append(w.runs, fptr)
+
+	out := bufio.NewWriter(fptr)
+	enc := gob.NewEncoder(out)
+	for _, c := range w.changes {
+		if err := enc.Encode(record{c.time, c.seq, c.v.index, c.value, c.toggle}); err != nil {
+			return err
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	w.changes = w.changes[:0]
+	return nil
+}
+
+// run reads the changes of a spilled file, or of the buffer if dec is nil,
+// in order
+type run struct {
+	dec  *gob.Decoder
+	mem  []change
+	curr change
+	ok   bool
+}
+
+func (r *run) next(vars []*variable) error {
+	if r.dec == nil {
+		r.ok = len(r.mem) > 0
+		if r.ok {
+			r.curr, r.mem = r.mem[0], r.mem[1:]
+		}
+		return nil
+	}
+
+	var rec record
+	if err := r.dec.Decode(&rec); err == io.EOF {
+		r.ok = false
+		return nil
+	} else if err != nil {
+		return err
+	}
+	r.curr = change{rec.Time, rec.Seq, vars[rec.Var], rec.Value, rec.Toggle}
+	r.ok = true
+	return nil
+}
+
+func (w *Writer) removeRuns() {
+	for _, fptr := range w.runs {
+		fptr.Close()
+		os.Remove(fptr.Name())
+	}
+	w.runs = nil
+}
+
+// Toggle flips a one bit wire at time t in ns, this is how requests and
+// acknowledgements are shown
+func (w *Writer) Toggle(path []string, t float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.add(path, Wire, 1, t, "", true)
+}
+
+// Value records a token at time t in ns. Structs and slices are split into
+// a scope per field or element the same way the top level logger splits
+// them, and a scalar token is recorded as "data".
+func (w *Writer) Value(path []string, t float64, value interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || (v.Kind() != reflect.Struct && v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+		path = append(path, "data")
+	}
+	w.value(path, t, v)
+}
+
+func binary(x uint64, width int) string {
+	if width < 64 {
+		x &= (uint64(1) << width) - 1
+	}
+	return "b" + strconv.FormatUint(x, 2)
+}
+
+func (w *Writer) value(path []string, t float64, v reflect.Value) {
+	if !v.IsValid() {
+		return
+	}
+
+	if v.CanInterface() {
+		// arbitrary precision integers have no fixed width
+		if b, ok := v.Interface().(*big.Int); ok && b != nil {
+			w.add(path, String, 1, t, "s"+b.String(), false)
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		value := "0"
+		if v.Bool() {
+			value = "1"
+		}
+		w.add(path, Wire, 1, t, value, false)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := v.Type().Bits()
+		w.add(path, Integer, bits, t, binary(uint64(v.Int()), bits), false)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		bits := v.Type().Bits()
+		w.add(path, Integer, bits, t, binary(v.Uint(), bits), false)
+	case reflect.Float32, reflect.Float64:
+		w.add(path, Real, 64, t, "r"+strconv.FormatFloat(v.Float(), 'g', -1, 64), false)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			w.value(append(path, strconv.Itoa(i)), t, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			w.value(append(path, v.Type().Field(i).Name), t, v.Field(i))
+		}
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			w.value(path, t, v.Elem())
+		}
+	default:
+		text := v.String()
+		if v.Kind() != reflect.String && v.CanInterface() {
+			text = fmt.Sprintf("%v", v.Interface())
+		}
+		w.add(path, String, 1, t, "s"+strings.ReplaceAll(text, " ", "_"), false)
+	}
+}
+
+func (v *variable) name() string {
+	return v.path[len(v.path)-1]
+}
+
+func (v *variable) format(value string) string {
+	if v.kind == Wire && v.width == 1 {
+		return value + v.id
+	}
+	return value + " " + v.id
+}
+
+func (v *variable) initial() string {
+	switch v.kind {
+	case Wire:
+		if v.width == 1 {
+			return v.format("0")
+		}
+		return v.format("bx")
+	case Real:
+		return v.format("r0")
+	case String:
+		return v.format("s")
+	}
+	return v.format("bx")
+}
+
+// writeScopes declares the variables, sorted by scope so that every scope
+// is opened once
+func (w *Writer) writeScopes(out *bufio.Writer) {
+	vars := append([]*variable{}, w.order...)
+	sort.SliceStable(vars, func(i, j int) bool {
+		a, b := vars[i].path, vars[j].path
+		for k := 0; k < len(a)-1 && k < len(b)-1; k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		// variables come before nested scopes
+		return len(a) < len(b)
+	})
+
+	var scope []string
+	for _, v := range vars {
+		dir := v.path[0 : len(v.path)-1]
+		common := 0
+		for common < len(scope) && common < len(dir) && scope[common] == dir[common] {
+			common++
+		}
+		for i := len(scope); i > common; i-- {
+			fmt.Fprintf(out, "$upscope $end\n")
+		}
+		for _, name := range dir[common:] {
+			fmt.Fprintf(out, "$scope module %s $end\n", name)
+		}
+		scope = dir
+
+		kind := v.kind
+		if kind == Integer {
+			kind = Wire
+		}
+		fmt.Fprintf(out, "$var %s %d %s %s $end\n", kind, v.width, v.id, v.name())
+	}
+	for range scope {
+		fmt.Fprintf(out, "$upscope $end\n")
+	}
+}
+
+// Close merges the recorded changes by time and writes the dump
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer w.removeRuns()
+	if w.err != nil {
+		return w.err
+	}
+
+	runs := make([]*run, 0, len(w.runs)+1)
+	for _, fptr := range w.runs {
+		if _, err := fptr.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		runs = append(runs, &run{dec: gob.NewDecoder(bufio.NewReader(fptr))})
+	}
+	w.sort()
+	runs = append(runs, &run{mem: w.changes})
+	for _, r := range runs {
+		if err := r.next(w.order); err != nil {
+			return err
+		}
+	}
+
+	fptr, err := os.Create(w.path)
+	if err != nil {
+		return err
+	}
+	defer fptr.Close()
+	out := bufio.NewWriter(fptr)
+
+	fmt.Fprintf(out, "$version pr $end\n")
+	fmt.Fprintf(out, "$timescale 1ps $end\n")
+	w.writeScopes(out)
+	fmt.Fprintf(out, "$enddefinitions $end\n")
+
+	fmt.Fprintf(out, "#0\n$dumpvars\n")
+	for _, v := range w.order {
+		fmt.Fprintf(out, "%s\n", v.initial())
+	}
+	fmt.Fprintf(out, "$end\n")
+
+	last := int64(0)
+	for {
+		var first *run
+		for _, r := range runs {
+			if r.ok && (first == nil || r.curr.before(first.curr)) {
+				first = r
+			}
+		}
+		if first == nil {
+			break
+		}
+		c := first.curr
+		if err := first.next(w.order); err != nil {
+			return err
+		}
+
+		if c.time != last {
+			fmt.Fprintf(out, "#%d\n", c.time)
+			last = c.time
+		}
+		value := c.value
+		if c.toggle {
+			c.v.level = !c.v.level
+			value = "0"
+			if c.v.level {
+				value = "1"
+			}
+		}
+		fmt.Fprintf(out, "%s\n", c.v.format(value))
+	}
+	w.changes = nil
+	return out.Flush()
+}
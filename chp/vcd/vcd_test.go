@@ -0,0 +1,111 @@
+package vcd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "top.vcd")
+	w, err := Create(path)
+	assert.NoError(t, err)
+
+	type token struct {
+		C bool
+		D []int8
+	}
+
+	L := Scope("top.src", "L")
+	R := Scope("top", "R.0")
+	w.Toggle(append(L, "req"), 0)
+	w.Value(L, 0, token{true, []int8{-1}})
+	w.Toggle(append(R, "req"), 0.5)
+	w.Value(R, 0.5, 2.5)
+	w.Toggle(append(L, "ack"), 1)
+	w.Toggle(append(L, "req"), 1.0005)
+	w.Value(L, 1.0005, token{false, []int8{3}})
+	assert.NoError(t, w.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `$version pr $end
+$timescale 1ps $end
+$scope module top $end
+$scope module R $end
+$scope module 0 $end
+$var wire 1 $ req $end
+$var real 64 % data $end
+$upscope $end
+$upscope $end
+$scope module src $end
+$scope module L $end
+$var wire 1 ! req $end
+$var wire 1 " C $end
+$var wire 1 & ack $end
+$scope module D $end
+$var wire 8 # 0 $end
+$upscope $end
+$upscope $end
+$upscope $end
+$upscope $end
+$enddefinitions $end
+#0
+$dumpvars
+0!
+0"
+bx #
+0$
+r0 %
+0&
+$end
+1!
+1"
+b11111111 #
+#500
+1$
+r2.5 %
+#1000
+1&
+#1001
+0!
+0"
+b11 #
+`, string(data))
+}
+
+func TestWriterSpill(t *testing.T) {
+	dir := t.TempDir()
+	dump := func(name string, limit int) string {
+		path := filepath.Join(dir, name)
+		w, err := Create(path)
+		assert.NoError(t, err)
+		w.limit = limit
+
+		// each process reports its own changes, out of order between them
+		A := Scope("top.a", "A")
+		B := Scope("top.b", "B")
+		for i := 0; i < 10; i++ {
+			w.Toggle(append(A, "req"), float64(2*i))
+			w.Value(A, float64(2*i), i)
+		}
+		for i := 0; i < 10; i++ {
+			w.Toggle(append(B, "req"), float64(2*i+1))
+			w.Toggle(append(A, "ack"), float64(2*i+1))
+		}
+		assert.NoError(t, w.Close())
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		return string(data)
+	}
+
+	expected := dump("buffered.vcd", maxBuffered)
+	assert.Equal(t, expected, dump("spilled.vcd", 3))
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
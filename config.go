@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// config holds the defaults shared by every command. It is read from the
+// first pr.toml or .prrc found in the working directory or one of its
+// parents, falling back to ~/.prrc. The file holds one "key = value" pair
+// per line, string values may be quoted and '#' starts a comment:
+//
+//	run = "run"                 # run directory written by chp.New
+//	profile = "top.prof"        # hand-written timing profile
+//	tech = "sky130"             # technology of the ACT layout configuration
+//	spice_tech = "sky130.tech"  # technology description used by spice
+//
+// Paths are relative to the config file.
+type config struct {
+	path string
+
+	Run       string
+	Profile   string
+	Tech      string
+	SpiceTech string
+}
+
+var configNames = []string{"pr.toml", ".prrc"}
+
+func newConfig() *config {
+	return &config{
+		Run:  "run",
+		Tech: "sky130",
+	}
+}
+
+func findConfig() string {
+	dir, err := os.Getwd()
+	if err == nil {
+		for {
+			for _, name := range configNames {
+				path := filepath.Join(dir, name)
+				if _, err := os.Stat(path); err == nil {
+					return path
+				}
+			}
+
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		path := filepath.Join(home, ".prrc")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+func loadConfig() (*config, error) {
+	c := newConfig()
+	path := findConfig()
+	if path == "" {
+		return c, nil
+	}
+
+	fptr, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fptr.Close()
+
+	c.path = path
+	scanner := bufio.NewScanner(fptr)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("%s:%d: expected 'key = value'", path, number)
+		}
+		key := strings.TrimSpace(line[0:eq])
+		value := strings.TrimSpace(line[eq+1:])
+		if strings.HasPrefix(value, "\"") {
+			end := strings.IndexByte(value[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("%s:%d: unterminated string", path, number)
+			}
+			value, err = strconv.Unquote(value[0 : end+2])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, number, err)
+			}
+		} else if i := strings.IndexByte(value, '#'); i >= 0 {
+			value = strings.TrimSpace(value[0:i])
+		}
+
+		relative := func(value string) string {
+			if value != "" && !filepath.IsAbs(value) {
+				return filepath.Join(filepath.Dir(path), value)
+			}
+			return value
+		}
+
+		switch key {
+		case "run":
+			c.Run = relative(value)
+		case "profile":
+			c.Profile = relative(value)
+		case "tech":
+			c.Tech = value
+		case "spice_tech":
+			c.SpiceTech = relative(value)
+		default:
+			return nil, fmt.Errorf("%s:%d: unrecognized key '%s'", path, number, key)
+		}
+	}
+	return c, scanner.Err()
+}
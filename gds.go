@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"git.broccolimicro.io/Broccoli/pr.git/act"
+	"git.broccolimicro.io/Broccoli/pr.git/layout"
+)
+
+var gdsCommand = &command{
+	name:  "gds",
+	usage: "[flags] <cell.rect>",
+	desc:  "convert a .rect cell into a GDSII stream using the ACT layout configuration",
+	setup: func(fs *flag.FlagSet, c *config) func(args []string) error {
+		tech := fs.String("T", c.Tech, "technology used for this translation")
+		conf := fs.String("conf", "", "ACT layout configuration (default $ACT_HOME/conf/<tech>/layout.conf)")
+		out := fs.String("o", ".", "output directory")
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usagef("gds: expected a .rect file")
+			}
+			if *conf == "" {
+				*conf = layoutConf(*tech)
+			}
+			return cellGDS(*conf, args[0], *out)
+		}
+	},
+}
+
+func cellGDS(confPath, rectPath, out string) error {
+	conf, err := act.Load(confPath)
+	if err != nil {
+		return err
+	}
+
+	cell, err := layout.LoadCell(rectPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(out, 0755)
+	if err != nil {
+		return err
+	}
+
+	return layout.GDS(conf, cell).Save(filepath.Join(out, cell.Name+".gds"))
+}
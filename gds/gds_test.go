@@ -0,0 +1,41 @@
+package gds
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReal(t *testing.T) {
+	// examples from the GDSII stream format manual
+	assert.Equal(t, uint64(0x4110000000000000), EncodeReal(1))
+	assert.Equal(t, uint64(0xC110000000000000), EncodeReal(-1))
+	assert.Equal(t, uint64(0x4220000000000000), EncodeReal(32))
+	assert.Equal(t, uint64(0x3F40000000000000), EncodeReal(1.0/64.0))
+
+	for _, x := range []float64{0, 1, 0.001, 5e-9, 1e-9, -12.75, 123456.5} {
+		assert.InEpsilon(t, x+1e-300, DecodeReal(EncodeReal(x))+1e-300, 1e-15)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	lib := NewLibrary("lib", 5e-9)
+	lib.Modified = time.Date(2024, 3, 4, 5, 6, 7, 0, time.Local)
+	s := lib.NewStructure("cell")
+	s.Rect(68, 20, -1, 0, 10, 20)
+	s.Label(68, 5, 4, 5, "odd")
+	s.Texts[0].Presentation = 5
+
+	var buf bytes.Buffer
+	assert.NoError(t, lib.Write(&buf))
+	assert.Equal(t, 0, buf.Len()%2)
+
+	result, err := Read(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, lib, result)
+
+	_, err = Read(bytes.NewReader([]byte{0, 6, HEADER, Int2, 2, 88}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,313 @@
+package gds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+type Point struct {
+	X int32
+	Y int32
+}
+
+type Boundary struct {
+	Layer    int16
+	DataType int16
+	// closed polygon, the last point is the same as the first
+	XY []Point
+}
+
+type Text struct {
+	Layer        int16
+	TextType     int16
+	Presentation int16
+	XY           Point
+	String       string
+}
+
+type Structure struct {
+	Name       string
+	Modified   time.Time
+	Boundaries []Boundary
+	Texts      []Text
+}
+
+type Library struct {
+	Name     string
+	Modified time.Time
+	// size of a database unit in user units
+	UserUnit float64
+	// size of a database unit in meters
+	DBUnit     float64
+	Structures []*Structure
+}
+
+func NewLibrary(name string, dbUnit float64) *Library {
+	return &Library{
+		Name:     name,
+		Modified: time.Now(),
+		UserUnit: 1,
+		DBUnit:   dbUnit,
+	}
+}
+
+func (l *Library) NewStructure(name string) *Structure {
+	s := &Structure{
+		Name:     name,
+		Modified: l.Modified,
+	}
+	l.Structures = append(l.Structures, s)
+	return s
+}
+
+func (l *Library) Structure(name string) *Structure {
+	for _, s := range l.Structures {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Rect adds a rectangle from (x0, y0) to (x1, y1)
+func (s *Structure) Rect(layer, dataType int16, x0, y0, x1, y1 int32) {
+	s.Boundaries = append(s.Boundaries, Boundary{
+		Layer:    layer,
+		DataType: dataType,
+		XY:       []Point{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}, {x0, y0}},
+	})
+}
+
+func (s *Structure) Label(layer, textType int16, x, y int32, str string) {
+	s.Texts = append(s.Texts, Text{
+		Layer:    layer,
+		TextType: textType,
+		XY:       Point{x, y},
+		String:   str,
+	})
+}
+
+func xy(points ...Point) Record {
+	values := make([]int32, 0, 2*len(points))
+	for _, p := range points {
+		values = append(values, p.X, p.Y)
+	}
+	return int4s(XY, values...)
+}
+
+func (s *Structure) records() []Record {
+	records := []Record{
+		timestamp(BGNSTR, s.Modified),
+		str(STRNAME, s.Name),
+	}
+	for _, b := range s.Boundaries {
+		records = append(records,
+			empty(BOUNDARY),
+			int2s(LAYER, b.Layer),
+			int2s(DATATYPE, b.DataType),
+			xy(b.XY...),
+			empty(ENDEL))
+	}
+	for _, t := range s.Texts {
+		records = append(records,
+			empty(TEXT),
+			int2s(LAYER, t.Layer),
+			int2s(TEXTTYPE, t.TextType))
+		if t.Presentation != 0 {
+			records = append(records, int2s(PRESENTATION, t.Presentation))
+		}
+		records = append(records,
+			xy(t.XY),
+			str(STRING, t.String),
+			empty(ENDEL))
+	}
+	return append(records, empty(ENDSTR))
+}
+
+// Write writes the library as a GDSII stream
+func (l *Library) Write(w io.Writer) error {
+	records := []Record{
+		int2s(HEADER, 600),
+		timestamp(BGNLIB, l.Modified),
+		str(LIBNAME, l.Name),
+		real8s(UNITS, l.UserUnit, l.DBUnit),
+	}
+	for _, s := range l.Structures {
+		records = append(records, s.records()...)
+	}
+	records = append(records, empty(ENDLIB))
+
+	for _, r := range records {
+		if err := WriteRecord(w, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Library) Save(path string) error {
+	fptr, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer fptr.Close()
+
+	w := bufio.NewWriter(fptr)
+	if err := l.Write(w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func parseTime(values []int16) time.Time {
+	if len(values) < 6 {
+		return time.Time{}
+	}
+	return time.Date(int(values[0]), time.Month(values[1]), int(values[2]),
+		int(values[3]), int(values[4]), int(values[5]), 0, time.Local)
+}
+
+func points(r Record) []Point {
+	values := r.Int4s()
+	result := make([]Point, len(values)/2)
+	for i := range result {
+		result[i] = Point{values[2*i], values[2*i+1]}
+	}
+	return result
+}
+
+func readBoundary(r io.Reader) (Boundary, error) {
+	var b Boundary
+	for {
+		rec, err := ReadRecord(r)
+		if err != nil {
+			return b, err
+		}
+		switch rec.Type {
+		case LAYER:
+			b.Layer = rec.Int2()
+		case DATATYPE:
+			b.DataType = rec.Int2()
+		case XY:
+			b.XY = points(rec)
+		case ENDEL:
+			return b, nil
+		}
+	}
+}
+
+func readText(r io.Reader) (Text, error) {
+	var t Text
+	for {
+		rec, err := ReadRecord(r)
+		if err != nil {
+			return t, err
+		}
+		switch rec.Type {
+		case LAYER:
+			t.Layer = rec.Int2()
+		case TEXTTYPE:
+			t.TextType = rec.Int2()
+		case PRESENTATION:
+			t.Presentation = rec.Int2()
+		case XY:
+			if p := points(rec); len(p) > 0 {
+				t.XY = p[0]
+			}
+		case STRING:
+			t.String = rec.Text()
+		case ENDEL:
+			return t, nil
+		}
+	}
+}
+
+// skipElement skips the records of an element that isn't supported
+func skipElement(r io.Reader) error {
+	for {
+		rec, err := ReadRecord(r)
+		if err != nil {
+			return err
+		} else if rec.Type == ENDEL {
+			return nil
+		}
+	}
+}
+
+func readStructure(r io.Reader, s *Structure) error {
+	for {
+		rec, err := ReadRecord(r)
+		if err != nil {
+			return err
+		}
+		switch rec.Type {
+		case STRNAME:
+			s.Name = rec.Text()
+		case BOUNDARY:
+			b, err := readBoundary(r)
+			if err != nil {
+				return err
+			}
+			s.Boundaries = append(s.Boundaries, b)
+		case TEXT:
+			t, err := readText(r)
+			if err != nil {
+				return err
+			}
+			s.Texts = append(s.Texts, t)
+		case PATH, SREF, AREF:
+			if err := skipElement(r); err != nil {
+				return err
+			}
+		case ENDSTR:
+			return nil
+		}
+	}
+}
+
+// Read reads a GDSII stream. Only boundaries and text are kept, other
+// elements are skipped.
+func Read(r io.Reader) (*Library, error) {
+	l := &Library{}
+	for {
+		rec, err := ReadRecord(r)
+		if err == io.EOF {
+			return nil, fmt.Errorf("missing ENDLIB")
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch rec.Type {
+		case BGNLIB:
+			l.Modified = parseTime(rec.Int2s())
+		case LIBNAME:
+			l.Name = rec.Text()
+		case UNITS:
+			units := rec.Real8s()
+			if len(units) != 2 {
+				return nil, fmt.Errorf("expected two units, found %d", len(units))
+			}
+			l.UserUnit, l.DBUnit = units[0], units[1]
+		case BGNSTR:
+			s := &Structure{Modified: parseTime(rec.Int2s())}
+			if err := readStructure(r, s); err != nil {
+				return nil, err
+			}
+			l.Structures = append(l.Structures, s)
+		case ENDLIB:
+			return l, nil
+		}
+	}
+}
+
+func Load(path string) (*Library, error) {
+	fptr, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fptr.Close()
+	return Read(bufio.NewReader(fptr))
+}
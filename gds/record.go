@@ -0,0 +1,203 @@
+package gds
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// record types
+const (
+	HEADER       = 0x00
+	BGNLIB       = 0x01
+	LIBNAME      = 0x02
+	UNITS        = 0x03
+	ENDLIB       = 0x04
+	BGNSTR       = 0x05
+	STRNAME      = 0x06
+	ENDSTR       = 0x07
+	BOUNDARY     = 0x08
+	PATH         = 0x09
+	SREF         = 0x0A
+	AREF         = 0x0B
+	TEXT         = 0x0C
+	LAYER        = 0x0D
+	DATATYPE     = 0x0E
+	XY           = 0x10
+	ENDEL        = 0x11
+	TEXTTYPE     = 0x16
+	PRESENTATION = 0x17
+	STRING       = 0x19
+)
+
+// data types
+const (
+	NoData  = 0x00
+	BitData = 0x01
+	Int2    = 0x02
+	Int4    = 0x03
+	Real4   = 0x04
+	Real8   = 0x05
+	ASCII   = 0x06
+)
+
+type Record struct {
+	Type     byte
+	DataType byte
+	Data     []byte
+}
+
+// EncodeReal converts a float to the excess-64 base-16 format used by GDSII
+func EncodeReal(x float64) uint64 {
+	if x == 0 {
+		return 0
+	}
+
+	var sign uint64
+	if x < 0 {
+		sign = 1 << 63
+		x = -x
+	}
+
+	exp := 64
+	for x >= 1 {
+		x /= 16
+		exp++
+	}
+	for x < 1.0/16.0 {
+		x *= 16
+		exp--
+	}
+
+	mantissa := uint64(math.Round(x * (1 << 56)))
+	if mantissa >= 1<<56 {
+		mantissa >>= 4
+		exp++
+	}
+	return sign | uint64(exp)<<56 | mantissa
+}
+
+func DecodeReal(v uint64) float64 {
+	mantissa := float64(v & (1<<56 - 1))
+	exp := int((v>>56)&0x7F) - 64
+	x := mantissa / (1 << 56) * math.Pow(16, float64(exp))
+	if v&(1<<63) != 0 {
+		return -x
+	}
+	return x
+}
+
+func WriteRecord(w io.Writer, r Record) error {
+	if len(r.Data)+4 > math.MaxUint16 {
+		return fmt.Errorf("record 0x%02x too long", r.Type)
+	}
+	header := []byte{0, 0, r.Type, r.DataType}
+	binary.BigEndian.PutUint16(header, uint16(len(r.Data)+4))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(r.Data)
+	return err
+}
+
+func ReadRecord(r io.Reader) (Record, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return Record{}, err
+	}
+	length := int(binary.BigEndian.Uint16(header))
+	if length < 4 || length%2 != 0 {
+		return Record{}, fmt.Errorf("invalid record length %d", length)
+	}
+	rec := Record{
+		Type:     header[2],
+		DataType: header[3],
+		Data:     make([]byte, length-4),
+	}
+	_, err := io.ReadFull(r, rec.Data)
+	return rec, err
+}
+
+func empty(typ byte) Record {
+	return Record{Type: typ, DataType: NoData}
+}
+
+func int2s(typ byte, values ...int16) Record {
+	data := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(data[2*i:], uint16(v))
+	}
+	return Record{typ, Int2, data}
+}
+
+func int4s(typ byte, values ...int32) Record {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(data[4*i:], uint32(v))
+	}
+	return Record{typ, Int4, data}
+}
+
+func real8s(typ byte, values ...float64) Record {
+	data := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint64(data[8*i:], EncodeReal(v))
+	}
+	return Record{typ, Real8, data}
+}
+
+// str pads the string to an even length with a null character
+func str(typ byte, value string) Record {
+	data := []byte(value)
+	if len(data)%2 != 0 {
+		data = append(data, 0)
+	}
+	return Record{typ, ASCII, data}
+}
+
+func timestamp(typ byte, t time.Time) Record {
+	return int2s(typ,
+		int16(t.Year()), int16(t.Month()), int16(t.Day()),
+		int16(t.Hour()), int16(t.Minute()), int16(t.Second()),
+		int16(t.Year()), int16(t.Month()), int16(t.Day()),
+		int16(t.Hour()), int16(t.Minute()), int16(t.Second()))
+}
+
+func (r Record) Int2s() []int16 {
+	result := make([]int16, len(r.Data)/2)
+	for i := range result {
+		result[i] = int16(binary.BigEndian.Uint16(r.Data[2*i:]))
+	}
+	return result
+}
+
+// Int2 returns the first value of an Int2 record
+func (r Record) Int2() int16 {
+	if len(r.Data) < 2 {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(r.Data))
+}
+
+func (r Record) Int4s() []int32 {
+	result := make([]int32, len(r.Data)/4)
+	for i := range result {
+		result[i] = int32(binary.BigEndian.Uint32(r.Data[4*i:]))
+	}
+	return result
+}
+
+func (r Record) Real8s() []float64 {
+	result := make([]float64, len(r.Data)/8)
+	for i := range result {
+		result[i] = DecodeReal(binary.BigEndian.Uint64(r.Data[8*i:]))
+	}
+	return result
+}
+
+func (r Record) Text() string {
+	return strings.TrimRight(string(r.Data), "\x00")
+}
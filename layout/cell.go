@@ -0,0 +1,120 @@
+package layout
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Kind int
+
+const (
+	Tap Kind = iota
+	Fill
+	Core
+	Block
+)
+
+type Rect struct {
+	Label string
+	Layer string
+	// left, bottom, right, top
+	Bounds   [4]int64
+	Hint     string
+	IsInput  bool
+	IsOutput bool
+}
+
+type Cell struct {
+	Name string
+	Kind Kind
+	// left, bottom, right, top
+	BBox  [4]int64
+	Rects []Rect
+}
+
+func (c *Cell) IsStdCell() bool {
+	return c.Kind == Tap || c.Kind == Fill || c.Kind == Core
+}
+
+// ReadCell reads a .rect file. The cell's kind is derived from its name.
+func ReadCell(name string, r io.Reader) (*Cell, error) {
+	c := &Cell{
+		Name: name,
+		Kind: Block,
+		BBox: [4]int64{0, 0, 1, 1},
+	}
+
+	lower := strings.ToLower(name)
+	if strings.Contains(lower, "welltap") {
+		c.Kind = Tap
+	} else if strings.Contains(lower, "fill") {
+		c.Kind = Fill
+	} else if strings.Contains(lower, "cell") {
+		c.Kind = Core
+	}
+
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			continue
+		}
+
+		if args[0] == "bbox" {
+			if len(args) != 5 {
+				return nil, fmt.Errorf("%d: expected 'bbox <left> <bottom> <right> <top>'", number)
+			}
+			for i := 0; i < 4; i++ {
+				v, err := strconv.ParseInt(args[i+1], 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("%d: %w", number, err)
+				}
+				c.BBox[i] = v
+			}
+			continue
+		}
+
+		if len(args) < 7 {
+			return nil, fmt.Errorf("%d: expected '<rect|inrect|outrect> <label> <layer> <left> <bottom> <right> <top> [hint]'", number)
+		}
+
+		rect := Rect{
+			Label:    args[1],
+			Layer:    args[2],
+			IsInput:  args[0] == "inrect",
+			IsOutput: args[0] == "outrect",
+		}
+		for i := 0; i < 4; i++ {
+			v, err := strconv.ParseInt(args[i+3], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", number, err)
+			}
+			rect.Bounds[i] = v
+		}
+		if len(args) >= 8 {
+			rect.Hint = args[7]
+		}
+		c.Rects = append(c.Rects, rect)
+	}
+	return c, scanner.Err()
+}
+
+func LoadCell(path string) (*Cell, error) {
+	fptr, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fptr.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	c, err := ReadCell(name, fptr)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,83 @@
+package layout
+
+import (
+	"io"
+	"math"
+
+	"git.broccolimicro.io/Broccoli/pr.git/act"
+	"git.broccolimicro.io/Broccoli/pr.git/gds"
+)
+
+type layerIndex struct {
+	Name  string
+	Major int16
+	Minor int16
+}
+
+// gdsLayers maps the gds layer names in the configuration to their major
+// and minor numbers in the order they were declared
+func gdsLayers(conf act.Section) ([]layerIndex, map[string]int) {
+	layers := conf.StringTable("gds.layers")
+	major := conf.IntTable("gds.major")
+	minor := conf.IntTable("gds.minor")
+
+	var order []layerIndex
+	index := map[string]int{}
+	for i := 0; i < len(layers) && i < len(major) && i < len(minor); i++ {
+		idx := layerIndex{layers[i], int16(major[i]), int16(minor[i])}
+		if j, ok := index[layers[i]]; ok {
+			order[j] = idx
+		} else {
+			index[layers[i]] = len(order)
+			order = append(order, idx)
+		}
+	}
+	return order, index
+}
+
+// GDS lays out the cell in a new gds library. The database unit is one
+// lambda, general.scale nanometers.
+func GDS(conf act.Section, cell *Cell) *gds.Library {
+	scale, ok := conf.Real("general.scale")
+	if !ok {
+		scale = 1
+	}
+
+	lib := gds.NewLibrary("library", scale*1e-9)
+	s := lib.NewStructure(cell.Name)
+
+	layers, index := gdsLayers(conf)
+	for _, layer := range layers {
+		name, purpose := splitLayer(layer.Name)
+		if (name == "text" && oneOf(purpose, "drawing", "dg", "drw")) || name == "outline" || name == "areaid_sc" {
+			s.Rect(layer.Major, layer.Minor,
+				int32(cell.BBox[0]), int32(cell.BBox[1]),
+				int32(cell.BBox[2]), int32(cell.BBox[3]))
+		}
+	}
+
+	for _, rect := range cell.Rects {
+		labelWritten := false
+		for _, layer := range queryGDS(conf, rect.Layer) {
+			i, ok := index[layer.Name]
+			if !ok {
+				continue
+			}
+			idx := layers[i]
+			s.Rect(idx.Major, idx.Minor,
+				int32(rect.Bounds[0]-layer.Bloat), int32(rect.Bounds[1]-layer.Bloat),
+				int32(rect.Bounds[2]+layer.Bloat), int32(rect.Bounds[3]+layer.Bloat))
+			if rect.Label != "" && rect.Label != "#" && !labelWritten {
+				x := math.Round(float64(rect.Bounds[0]+rect.Bounds[2]) / 2)
+				y := math.Round(float64(rect.Bounds[1]+rect.Bounds[3]) / 2)
+				s.Label(idx.Major, idx.Minor, int32(x), int32(y), rect.Label)
+				labelWritten = true
+			}
+		}
+	}
+	return lib
+}
+
+func WriteGDS(w io.Writer, conf act.Section, cell *Cell) error {
+	return GDS(conf, cell).Write(w)
+}
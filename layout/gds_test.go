@@ -0,0 +1,42 @@
+package layout
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"git.broccolimicro.io/Broccoli/pr.git/act"
+	"git.broccolimicro.io/Broccoli/pr.git/gds"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteGDS(t *testing.T) {
+	conf, err := act.Read(strings.NewReader(testConf + `begin gds
+  string_table layers "met1.drawing" "met1.label" "outline" "areaid_sc.identifier"
+  int_table major 68 68 236 81
+  int_table minor 20 5 0 4
+end
+`))
+	assert.NoError(t, err)
+	c, err := ReadCell("inv_cell", strings.NewReader("bbox -1 0 10 20\noutrect Y m1 1 1 2 2\nrect # m1 3 3 4 4\n"))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteGDS(&buf, conf, c))
+
+	lib, err := gds.Read(&buf)
+	assert.NoError(t, err)
+	assert.InEpsilon(t, 5e-9, lib.DBUnit, 1e-12)
+	assert.Equal(t, 1.0, lib.UserUnit)
+
+	s := lib.Structure("inv_cell")
+	assert.NotNil(t, s)
+	expect := &gds.Structure{}
+	expect.Rect(236, 0, -1, 0, 10, 20)
+	expect.Rect(81, 4, -1, 0, 10, 20)
+	expect.Rect(68, 20, 0, 0, 3, 3)
+	expect.Rect(68, 20, 2, 2, 5, 5)
+	expect.Label(68, 20, 2, 2, "Y")
+	assert.Equal(t, expect.Boundaries, s.Boundaries)
+	assert.Equal(t, expect.Texts, s.Texts)
+}
@@ -0,0 +1,236 @@
+package layout
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"git.broccolimicro.io/Broccoli/pr.git/act"
+)
+
+// pyFloat formats a float the way python's repr does so that the output
+// matches the original rect2lef script
+func pyFloat(x float64) string {
+	abs := math.Abs(x)
+	if abs != 0 && (abs < 1e-4 || abs >= 1e16) {
+		return strconv.FormatFloat(x, 'e', -1, 64)
+	}
+	str := strconv.FormatFloat(x, 'f', -1, 64)
+	if !strings.ContainsAny(str, ".") {
+		str += ".0"
+	}
+	return str
+}
+
+// scaler converts lambda units to microns using general.scale
+type scaler struct {
+	real  float64
+	whole int64
+	isInt bool
+}
+
+func newScaler(conf act.Section) scaler {
+	v, _ := conf.Lookup("general.scale")
+	switch s := v.(type) {
+	case int64:
+		return scaler{whole: s, isInt: true}
+	case float64:
+		return scaler{real: s}
+	}
+	return scaler{whole: 1, isInt: true}
+}
+
+func (s scaler) format(x int64) string {
+	if s.isInt {
+		return strconv.FormatInt(x*s.whole, 10)
+	}
+	return pyFloat(float64(x) * s.real)
+}
+
+type gdsLayer struct {
+	Name  string
+	Bloat int64
+}
+
+// splitLayer separates a gds layer name like "met1.drawing" into its name
+// and purpose
+func splitLayer(layer string) (string, string) {
+	if i := strings.LastIndex(layer, "."); i >= 0 {
+		return layer[0:i], layer[i+1:]
+	}
+	return layer, ""
+}
+
+// queryGDS finds the gds layers and their bloat for a layer in the .rect
+// file
+func queryGDS(conf act.Section, rectLayer string) []gdsLayer {
+	materials := conf.Section("materials")
+	var gds []string
+	var bloat []int64
+	if sec, ok := materials[rectLayer].(act.Section); ok {
+		gds, _ = sec["gds"].([]string)
+		bloat, _ = sec["gds_bloat"].([]int64)
+	} else if metal := materials.Section("metal"); metal != nil {
+		gds, _ = metal[rectLayer+"_gds"].([]string)
+		bloat, _ = metal[rectLayer+"_gds_bloat"].([]int64)
+	}
+
+	n := len(gds)
+	if len(bloat) < n {
+		n = len(bloat)
+	}
+	result := make([]gdsLayer, n)
+	for i := 0; i < n; i++ {
+		result[i] = gdsLayer{gds[i], bloat[i]}
+	}
+	return result
+}
+
+func containsAny(str string, searches ...string) bool {
+	for _, search := range searches {
+		if strings.Contains(str, search) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s scaler) rect(r Rect, bloat int64) string {
+	return fmt.Sprintf("RECT %s %s %s %s ;",
+		s.format(r.Bounds[0]-bloat), s.format(r.Bounds[1]-bloat),
+		s.format(r.Bounds[2]+bloat), s.format(r.Bounds[3]+bloat))
+}
+
+// WriteLEF writes the cell as a LEF macro. See
+// https://github.com/KLayout/klayout/blob/766dd675c11d98b2461c448035197f6e934cb497/src/plugins/streamers/lefdef/db_plugin/dbLEFDEFImporter.cc#L1085
+// for the placement codes that the layermap maps to.
+func WriteLEF(w io.Writer, conf act.Section, cell *Cell) error {
+	s := newScaler(conf)
+
+	fmt.Fprintf(w, "MACRO %s\n", cell.Name)
+	switch cell.Kind {
+	case Tap:
+		fmt.Fprintf(w, "CLASS CORE WELLTAP ;\n")
+	case Fill:
+		fmt.Fprintf(w, "CLASS CORE SPACER ;\n")
+	case Core:
+		fmt.Fprintf(w, "CLASS CORE ;\n")
+	default:
+		fmt.Fprintf(w, "CLASS BLOCK ;\n")
+	}
+
+	fmt.Fprintf(w, "\tORIGIN %s %s ;\n", s.format(-cell.BBox[0]), s.format(-cell.BBox[1]))
+	fmt.Fprintf(w, "\tFOREIGN %s %s %s ;\n", cell.Name, s.format(cell.BBox[0]), s.format(cell.BBox[1]))
+	fmt.Fprintf(w, "\tSIZE %s BY %s ;\n", s.format(cell.BBox[2]-cell.BBox[0]), s.format(cell.BBox[3]-cell.BBox[1]))
+	fmt.Fprintf(w, "\tSYMMETRY X Y ;\n")
+	if cell.IsStdCell() {
+		fmt.Fprintf(w, "\tSITE CoreSite ;\n")
+	}
+
+	for _, rect := range cell.Rects {
+		if !rect.IsInput && !rect.IsOutput {
+			continue
+		}
+
+		direction := "INOUT"
+		if !rect.IsInput {
+			direction = "OUTPUT"
+		} else if !rect.IsOutput {
+			direction = "INPUT"
+		}
+
+		fmt.Fprintf(w, "\tPIN %s\n", rect.Label)
+		label := strings.ToLower(rect.Label)
+		if containsAny(label, "vnsub", "vpsub", "vddsub", "vsssub", "vddb", "vssb") {
+			fmt.Fprintf(w, "\t\tDIRECTION INOUT ;\n")
+			fmt.Fprintf(w, "\t\tUSE POWER ;\n")
+		} else if containsAny(label, "vdd", "pwr") {
+			fmt.Fprintf(w, "\t\tDIRECTION INOUT ;\n")
+			fmt.Fprintf(w, "\t\tUSE POWER ;\n")
+			if cell.IsStdCell() {
+				fmt.Fprintf(w, "\t\tSHAPE ABUTMENT ;\n")
+			}
+		} else if containsAny(label, "gnd", "vss") {
+			fmt.Fprintf(w, "\t\tDIRECTION INOUT ;\n")
+			fmt.Fprintf(w, "\t\tUSE GROUND ;\n")
+			if cell.IsStdCell() {
+				fmt.Fprintf(w, "\t\tSHAPE ABUTMENT ;\n")
+			}
+		} else {
+			fmt.Fprintf(w, "\t\tDIRECTION %s ;\n", direction)
+			fmt.Fprintf(w, "\t\tUSE SIGNAL ;\n")
+		}
+
+		fmt.Fprintf(w, "\t\tPORT\n")
+		for _, layer := range queryGDS(conf, rect.Layer) {
+			name, _ := splitLayer(layer.Name)
+			fmt.Fprintf(w, "\t\t\tLAYER %s ;\n", name)
+			fmt.Fprintf(w, "\t\t\t\t%s\n", s.rect(rect, layer.Bloat))
+		}
+		fmt.Fprintf(w, "\t\tEND\n")
+		fmt.Fprintf(w, "\tEND %s\n", rect.Label)
+	}
+
+	fmt.Fprintf(w, "\tOBS\n")
+	for _, rect := range cell.Rects {
+		for _, layer := range queryGDS(conf, rect.Layer) {
+			name, _ := splitLayer(layer.Name)
+			fmt.Fprintf(w, "\t\tLAYER %s ;\n", name)
+			fmt.Fprintf(w, "\t\t\t%s\n", s.rect(rect, layer.Bloat))
+		}
+	}
+	fmt.Fprintf(w, "\tEND\n")
+	fmt.Fprintf(w, "END %s\n", cell.Name)
+	_, err := fmt.Fprintf(w, "\n")
+	return err
+}
+
+func oneOf(str string, options ...string) bool {
+	for _, option := range options {
+		if str == option {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteLayerMap writes a KLayout layer map for the gds layers in the
+// configuration
+func WriteLayerMap(w io.Writer, conf act.Section) error {
+	layers := conf.StringTable("gds.layers")
+	major := conf.IntTable("gds.major")
+	minor := conf.IntTable("gds.minor")
+	n := len(layers)
+	if len(major) < n {
+		n = len(major)
+	}
+	if len(minor) < n {
+		n = len(minor)
+	}
+
+	for i := 0; i < n; i++ {
+		name, purpose := splitLayer(layers[i])
+		if strings.Contains(name, "via") && oneOf(purpose, "drawing", "dg", "drw") {
+			fmt.Fprintf(w, "%s VIA %d %d\n", name, major[i], minor[i])
+		} else if oneOf(purpose, "drawing", "dg", "drw") {
+			fmt.Fprintf(w, "%s LEFOBS %d %d\n", name, major[i], minor[i])
+		} else if oneOf(purpose, "label", "ll", "lbl") {
+			fmt.Fprintf(w, "NAME %s/PINNAME %d %d\n", name, major[i], minor[i])
+			fmt.Fprintf(w, "NAME %s/PIN %d %d\n", name, major[i], minor[i])
+			fmt.Fprintf(w, "NAME %s/LEFPINNAME %d %d\n", name, major[i], minor[i])
+			fmt.Fprintf(w, "NAME %s/LEFPIN %d %d\n", name, major[i], minor[i])
+		} else if oneOf(purpose, "net", "nt") {
+			fmt.Fprintf(w, "%s NET %d %d\n", name, major[i], minor[i])
+		} else if oneOf(purpose, "pin", "pin1", "pn") {
+			fmt.Fprintf(w, "%s PIN,LEFPIN %d %d\n", name, major[i], minor[i])
+		} else if oneOf(purpose, "blockage", "be", "blo") {
+			fmt.Fprintf(w, "%s BLOCKAGE %d %d\n", name, major[i], minor[i])
+		}
+		if strings.Contains(strings.ToLower(name), "prb") {
+			fmt.Fprintf(w, "DIEAREA ALL %d %d\n", major[i], minor[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package layout
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"git.broccolimicro.io/Broccoli/pr.git/act"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConf = `begin general
+  real scale 5
+end
+begin gds
+  string_table layers "met1.drawing" "met1.label" "via.drawing" "prBoundary.drawing"
+  int_table major 68 68 67 235
+  int_table minor 20 5 44 4
+end
+begin materials
+  begin metal
+    string_table m1_gds "met1.drawing"
+    int_table m1_gds_bloat 1
+  end
+end
+`
+
+func TestReadCell(t *testing.T) {
+	c, err := ReadCell("inv_cell", strings.NewReader("bbox -1 0 10 20\n\ninrect A m1 1 1 2 2\nrect # m1 3 3 4 4 hint\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, Core, c.Kind)
+	assert.Equal(t, [4]int64{-1, 0, 10, 20}, c.BBox)
+	assert.Equal(t, 2, len(c.Rects))
+	assert.True(t, c.Rects[0].IsInput)
+	assert.Equal(t, "hint", c.Rects[1].Hint)
+
+	c, err = ReadCell("welltap_x1", strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Equal(t, Tap, c.Kind)
+	assert.Equal(t, [4]int64{0, 0, 1, 1}, c.BBox)
+}
+
+func TestWriteLEF(t *testing.T) {
+	conf, err := act.Read(strings.NewReader(testConf))
+	assert.NoError(t, err)
+	c, err := ReadCell("blk", strings.NewReader("bbox -1 0 10 20\noutrect Y m1 1 1 2 2\n"))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteLEF(&buf, conf, c))
+	assert.Equal(t, "MACRO blk\n"+
+		"CLASS BLOCK ;\n"+
+		"\tORIGIN 5.0 0.0 ;\n"+
+		"\tFOREIGN blk -5.0 0.0 ;\n"+
+		"\tSIZE 55.0 BY 100.0 ;\n"+
+		"\tSYMMETRY X Y ;\n"+
+		"\tPIN Y\n"+
+		"\t\tDIRECTION OUTPUT ;\n"+
+		"\t\tUSE SIGNAL ;\n"+
+		"\t\tPORT\n"+
+		"\t\t\tLAYER met1 ;\n"+
+		"\t\t\t\tRECT 0.0 0.0 15.0 15.0 ;\n"+
+		"\t\tEND\n"+
+		"\tEND Y\n"+
+		"\tOBS\n"+
+		"\t\tLAYER met1 ;\n"+
+		"\t\t\tRECT 0.0 0.0 15.0 15.0 ;\n"+
+		"\tEND\n"+
+		"END blk\n\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, WriteLayerMap(&buf, conf))
+	assert.Equal(t, "met1 LEFOBS 68 20\n"+
+		"NAME met1/PINNAME 68 5\n"+
+		"NAME met1/PIN 68 5\n"+
+		"NAME met1/LEFPINNAME 68 5\n"+
+		"NAME met1/LEFPIN 68 5\n"+
+		"via VIA 67 44\n"+
+		"prBoundary LEFOBS 235 4\n"+
+		"DIEAREA ALL 235 4\n", buf.String())
+}
+
+func TestPyFloat(t *testing.T) {
+	assert.Equal(t, "0.0", pyFloat(0))
+	assert.Equal(t, "0.015", pyFloat(3*0.005))
+	assert.Equal(t, "1e-05", pyFloat(0.00001))
+	assert.Equal(t, "-2.5", pyFloat(-2.5))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"git.broccolimicro.io/Broccoli/pr.git/act"
+	"git.broccolimicro.io/Broccoli/pr.git/layout"
+)
+
+// layoutConf finds the ACT layout configuration for a technology
+func layoutConf(tech string) string {
+	home := os.Getenv("ACT_HOME")
+	if home == "" {
+		home = "/opt/cad"
+	}
+	return filepath.Join(home, "conf", tech, "layout.conf")
+}
+
+var lefCommand = &command{
+	name:  "lef",
+	usage: "[flags] <cell.rect>",
+	desc:  "convert a .rect cell into LEF using the ACT layout configuration",
+	setup: func(fs *flag.FlagSet, c *config) func(args []string) error {
+		tech := fs.String("T", c.Tech, "technology used for this translation")
+		conf := fs.String("conf", "", "ACT layout configuration (default $ACT_HOME/conf/<tech>/layout.conf)")
+		doLM := fs.Bool("lm", false, "also write layermap.txt")
+		out := fs.String("o", ".", "output directory")
+		return func(args []string) error {
+			if len(args) > 1 {
+				return usagef("lef: expected at most one .rect file")
+			} else if len(args) == 0 && !*doLM {
+				return usagef("lef: expected a .rect file")
+			}
+			if *conf == "" {
+				*conf = layoutConf(*tech)
+			}
+			rect := ""
+			if len(args) > 0 {
+				rect = args[0]
+			}
+			return lef(*conf, rect, *doLM, *out)
+		}
+	},
+}
+
+func writeFile(path string, write func(fptr *os.File) error) error {
+	fptr, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer fptr.Close()
+	return write(fptr)
+}
+
+func lef(confPath, rectPath string, doLM bool, out string) error {
+	conf, err := act.Load(confPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(out, 0755)
+	if err != nil {
+		return err
+	}
+
+	if rectPath != "" {
+		cell, err := layout.LoadCell(rectPath)
+		if err != nil {
+			return err
+		}
+		err = writeFile(filepath.Join(out, cell.Name+".lef"), func(fptr *os.File) error {
+			return layout.WriteLEF(fptr, conf, cell)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if doLM {
+		return writeFile(filepath.Join(out, "layermap.txt"), func(fptr *os.File) error {
+			return layout.WriteLayerMap(fptr, conf)
+		})
+	}
+	return nil
+}
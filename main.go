@@ -1,39 +1,143 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 )
 
-func report(args ...string) {
+// command is a subcommand of pr. setup registers the command's flags, using
+// the shared config for their defaults, and returns the function that runs
+// the command on the remaining positional arguments.
+type command struct {
+	name  string
+	usage string
+	desc  string
+	setup func(fs *flag.FlagSet, c *config) func(args []string) error
 }
 
-func test(args ...string) {
+// usageError is returned by a command when it was given the wrong arguments
+type usageError struct {
+	msg string
 }
 
-func spice(args ...string) {
+func (e *usageError) Error() string {
+	return e.msg
 }
 
-func help(args ...string) {
-	fmt.Println("Production Rule: A self-timed circuit verification tool")
-	fmt.Println("usage: pr <command> <flags...>")
-	fmt.Println("")
-	fmt.Println("Commands:")
-	fmt.Println("  report - generate an aggregate performance report from the architectural simulation")
-	fmt.Println("  test   - use the architectural simulation to create inject and expect files for the digital simulator")
-	fmt.Println("  spice  - generate a spice simulation from that digital simulation for a particular process")
+func usagef(format string, args ...interface{}) error {
+	return &usageError{fmt.Sprintf(format, args...)}
 }
 
-func main() {
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		case "report": report(os.Args[2:len(os.Args)]...)
-		case "test": test(os.Args[2:len(os.Args)]...)
-		case "spice": spice(os.Args[2:len(os.Args)]...)
-		case "help": help(os.Args[2:len(os.Args)]...)
-		default: fmt.Printf("error: unrecognized command '%s'\n", os.Args[1])
+var commands []*command
+
+func init() {
+	commands = []*command{
+		reportCommand,
+		testCommand,
+		spiceCommand,
+		lefCommand,
+		gdsCommand,
+		characterizeCommand,
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
 		}
-	} else { 
-		help()
-	}	
+	}
+	return nil
+}
+
+func (cmd *command) flags(c *config) (*flag.FlagSet, func(args []string) error) {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	run := cmd.setup(fs, c)
+	return fs, run
+}
+
+func (cmd *command) help(w io.Writer, c *config) {
+	fs, _ := cmd.flags(c)
+	fmt.Fprintf(w, "usage: pr %s %s\n\n", cmd.name, cmd.usage)
+	fmt.Fprintf(w, "%s\n", cmd.desc)
+
+	hasFlags := false
+	fs.VisitAll(func(f *flag.Flag) {
+		hasFlags = true
+	})
+	if hasFlags {
+		fmt.Fprintf(w, "\nFlags:\n")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+}
+
+func help(w io.Writer, c *config, args ...string) error {
+	if len(args) > 0 {
+		cmd := findCommand(args[0])
+		if cmd == nil {
+			return usagef("unrecognized command '%s'", args[0])
+		}
+		cmd.help(w, c)
+		return nil
+	}
+
+	fmt.Fprintln(w, "Production Rule: A self-timed circuit verification tool")
+	fmt.Fprintln(w, "usage: pr <command> <flags...>")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-12s - %s\n", cmd.name, cmd.desc)
+	}
+	fmt.Fprintf(w, "  %-12s - %s\n", "help", "print the usage of a command")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Use 'pr help <command>' for more information about a command.")
+	fmt.Fprintf(w, "Defaults are read from %s or %s in the current directory or a parent, or from ~/.prrc.\n", configNames[0], configNames[1])
+	return nil
+}
+
+func execute(c *config, args []string) error {
+	if len(args) == 0 {
+		return help(os.Stdout, c)
+	} else if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		return help(os.Stdout, c, args[1:]...)
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		return usagef("unrecognized command '%s'", args[0])
+	}
+
+	fs, run := cmd.flags(c)
+	err := fs.Parse(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		cmd.help(os.Stdout, c)
+		return nil
+	} else if err != nil {
+		return usagef("%s: %v", cmd.name, err)
+	}
+	return run(fs.Args())
+}
+
+func main() {
+	c, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = execute(c, os.Args[1:])
+	var usage *usageError
+	if errors.As(err, &usage) {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "run 'pr help' for usage")
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
 }
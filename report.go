@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"git.broccolimicro.io/Broccoli/pr.git/run"
+)
+
+func writeSummaries(w io.Writer, title string, summaries []run.Summary) {
+	fmt.Fprintf(w, "%s\n", title)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "name\tprocs\ttokens\tcycle mean (ns)\tmin\tmax\tp50\tp90\tp99\tlatency mean (ns)\tthroughput (tokens/ns)\tenergy (fJ)\tenergy/token (fJ)\tpower (uW)\n")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%f\t%f\t%f\t%f\t%f\t%f\t%f\t%f\t%f\t%f\t%f\n",
+			s.Name, s.Processes, s.Tokens,
+			s.CycleTime.Mean, s.CycleTime.Min, s.CycleTime.Max,
+			s.CycleTime.P50, s.CycleTime.P90, s.CycleTime.P99,
+			s.Latency.Mean, s.Throughput,
+			s.Energy, s.EnergyPerToken, s.Power)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n")
+}
+
+func writeChannels(w io.Writer, channels []*run.Channel) {
+	fmt.Fprintf(w, "Channels\n")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "name\tdir\ttype\ttokens\tfirst (ns)\tlast (ns)\tcycle mean (ns)\tmin\tmax\tthroughput (tokens/ns)\n")
+	for _, c := range channels {
+		s := c.Summary()
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%f\t%f\t%f\t%f\t%f\t%f\n",
+			s.Name, c.Dir, c.Type, s.Tokens, s.Start, s.End,
+			s.CycleTime.Mean, s.CycleTime.Min, s.CycleTime.Max,
+			s.Throughput)
+	}
+	tw.Flush()
+}
+
+var reportCommand = &command{
+	name:  "report",
+	usage: "[flags] [run-dir]",
+	desc:  "generate an aggregate performance report from the architectural simulation",
+	setup: func(fs *flag.FlagSet, c *config) func(args []string) error {
+		dir := fs.String("run", c.Run, "run directory written by the simulation")
+		return func(args []string) error {
+			if len(args) > 1 {
+				return usagef("report: expected at most one run directory")
+			} else if len(args) > 0 {
+				*dir = args[0]
+			}
+			return report(*dir)
+		}
+	},
+}
+
+func report(dir string) error {
+	r, err := run.Load(dir)
+	if err != nil {
+		return err
+	}
+
+	var procs []run.Summary
+	for _, p := range r.Processes {
+		if len(p.Cycles) > 0 {
+			procs = append(procs, p.Summary())
+		}
+	}
+
+	writeSummaries(os.Stdout, "Processes", procs)
+	writeSummaries(os.Stdout, "Hierarchy", r.Levels())
+	writeChannels(os.Stdout, r.Channels)
+	return nil
+}
@@ -0,0 +1,355 @@
+package run
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/trace"
+)
+
+const (
+	CycleHeader   = "Start\tEnd\tEnergy (fJ)"
+	ChannelHeader = "time (ns)\t"
+)
+
+type Direction int
+
+const (
+	Send Direction = iota
+	Recv
+)
+
+func (d Direction) String() string {
+	if d == Send {
+		return "send"
+	}
+	return "recv"
+}
+
+// Suffix returns the file extension used by chp for channel logs in this
+// direction
+func (d Direction) Suffix() string {
+	if d == Send {
+		return ".s"
+	}
+	return ".r"
+}
+
+// Cycle is one line of the table written by Globals.Cycle
+type Cycle struct {
+	Start  float64
+	End    float64
+	Energy float64
+}
+
+type Process struct {
+	Name   string
+	Cycles []Cycle
+}
+
+type Token struct {
+	Time  float64
+	Value Value
+}
+
+// Channel is the log of one end of a channel as seen by a single process
+type Channel struct {
+	Process string
+	Name    string
+	Dir     Direction
+	Type    Type
+	Tokens  []Token
+}
+
+func (c *Channel) FullName() string {
+	return c.Process + "." + c.Name
+}
+
+// Run holds everything the architectural simulation wrote into a run
+// directory
+type Run struct {
+	Dir       string
+	Processes []*Process
+	Channels  []*Channel
+}
+
+// Load reads every cycle log and channel log in dir. Files are classified
+// by their header rather than by their name since process names and channel
+// names may both contain dots. If the simulation wrote a structured trace,
+// that is read instead unless a log is newer, which means the trace was
+// left behind by an earlier run.
+func Load(dir string) (*Run, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var cycles, logs []string
+	var newest time.Time
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == trace.JSONFile || entry.Name() == trace.BinaryFile {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		header, err := readHeader(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if header == CycleHeader {
+			cycles = append(cycles, path)
+		} else if strings.HasPrefix(header, ChannelHeader) && (strings.HasSuffix(path, ".s") || strings.HasSuffix(path, ".r")) {
+			logs = append(logs, path)
+		} else {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+
+	if path := trace.Find(dir); path != "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.ModTime().Before(newest) {
+			return loadTrace(dir, path)
+		}
+	}
+
+	r := &Run{
+		Dir: dir,
+	}
+	for _, path := range cycles {
+		p, err := loadProcess(path)
+		if err != nil {
+			return nil, err
+		}
+		r.Processes = append(r.Processes, p)
+	}
+
+	r.sortProcesses()
+	for _, path := range logs {
+		c, err := r.loadChannel(path)
+		if err != nil {
+			return nil, err
+		}
+		r.Channels = append(r.Channels, c)
+	}
+	r.sortProcesses()
+	r.sortChannels()
+	return r, nil
+}
+
+func (r *Run) sortProcesses() {
+	sort.Slice(r.Processes, func(i, j int) bool {
+		return r.Processes[i].Name < r.Processes[j].Name
+	})
+}
+
+func (r *Run) sortChannels() {
+	sort.Slice(r.Channels, func(i, j int) bool {
+		if r.Channels[i].Process != r.Channels[j].Process {
+			return r.Channels[i].Process < r.Channels[j].Process
+		}
+		if r.Channels[i].Name != r.Channels[j].Name {
+			return r.Channels[i].Name < r.Channels[j].Name
+		}
+		return r.Channels[i].Dir < r.Channels[j].Dir
+	})
+}
+
+// Process returns the process with the given hierarchical name or nil
+func (r *Run) Process(name string) *Process {
+	i := sort.Search(len(r.Processes), func(i int) bool {
+		return r.Processes[i].Name >= name
+	})
+	if i < len(r.Processes) && r.Processes[i].Name == name {
+		return r.Processes[i]
+	}
+	return nil
+}
+
+// addProcess returns the named process, adding it in sorted order if it
+// isn't known yet so that Process keeps finding it
+func (r *Run) addProcess(name string) *Process {
+	i := sort.Search(len(r.Processes), func(i int) bool {
+		return r.Processes[i].Name >= name
+	})
+	if i < len(r.Processes) && r.Processes[i].Name == name {
+		return r.Processes[i]
+	}
+	p := &Process{
+		Name: name,
+	}
+	r.Processes = append(r.Processes, nil)
+	copy(r.Processes[i+1:], r.Processes[i:])
+	r.Processes[i] = p
+	return p
+}
+
+// Ports returns the channels logged by the named process
+func (r *Run) Ports(name string) []*Channel {
+	var result []*Channel
+	for _, c := range r.Channels {
+		if c.Process == name {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func readHeader(path string) (string, error) {
+	fptr, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fptr.Close()
+
+	reader := bufio.NewReader(fptr)
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		// empty or unreadable files are not logs
+		return "", nil
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func loadProcess(path string) (*Process, error) {
+	fptr, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fptr.Close()
+
+	p := &Process{
+		Name: filepath.Base(path),
+	}
+
+	scanner := bufio.NewScanner(fptr)
+	for number := 1; scanner.Scan(); number++ {
+		if number == 1 {
+			continue
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		} else if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected 3 columns, found %d", path, number, len(fields))
+		}
+
+		var values [3]float64
+		for i, field := range fields {
+			values[i], err = strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, number, err)
+			}
+		}
+		p.Cycles = append(p.Cycles, Cycle{
+			Start:  values[0],
+			End:    values[1],
+			Energy: values[2],
+		})
+	}
+	return p, scanner.Err()
+}
+
+// splitName separates a channel log name of the form <process>.<channel>
+// into its two parts. The longest known process name wins. Processes that
+// never called Cycle have no log of their own, so otherwise the channel is
+// assumed to be the last component of the name, along with its index if it
+// came from ChanArr or BusArr.
+func (r *Run) splitName(name string) (string, string) {
+	proc := ""
+	for _, p := range r.Processes {
+		if strings.HasPrefix(name, p.Name+".") && len(p.Name) > len(proc) {
+			proc = p.Name
+		}
+	}
+	if proc != "" {
+		return proc, name[len(proc)+1:]
+	}
+
+	parts := strings.Split(name, ".")
+	n := 1
+	if len(parts) > 2 {
+		if _, err := strconv.Atoi(parts[len(parts)-1]); err == nil {
+			n = 2
+		}
+	}
+	if len(parts) <= n {
+		return "", name
+	}
+	return strings.Join(parts[0:len(parts)-n], "."), strings.Join(parts[len(parts)-n:], ".")
+}
+
+func (r *Run) loadChannel(path string) (*Channel, error) {
+	fptr, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fptr.Close()
+
+	base := filepath.Base(path)
+	c := &Channel{
+		Dir: Send,
+	}
+	if strings.HasSuffix(base, ".r") {
+		c.Dir = Recv
+	}
+	c.Process, c.Name = r.splitName(base[0 : len(base)-2])
+	if c.Process != "" {
+		r.addProcess(c.Process)
+	}
+
+	scanner := bufio.NewScanner(fptr)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Text()
+		if number == 1 {
+			c.Type, err = ParseType(strings.TrimPrefix(line, ChannelHeader))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, number, err)
+			}
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		column := strings.IndexByte(line, '\t')
+		if column < 0 {
+			return nil, fmt.Errorf("%s:%d: expected a tab separated time and value", path, number)
+		}
+
+		t, err := strconv.ParseFloat(line[0:column], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, number, err)
+		}
+
+		v, err := c.Type.Parse(line[column+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, number, err)
+		}
+
+		c.Tokens = append(c.Tokens, Token{
+			Time:  t,
+			Value: v,
+		})
+	}
+	return c, scanner.Err()
+}
@@ -0,0 +1,175 @@
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/trace"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	assert.NoError(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "top.dut", "Start\tEnd\tEnergy (fJ)\n"+
+		"0.000000\t0.500000\t10.000000\n"+
+		"0.500000\t1.000000\t10.000000\n"+
+		"1.000000\t2.000000\t20.000000\n")
+	writeFile(t, dir, "top.dut.L.r", "time (ns)\t{C:bool D:[int64]}\n"+
+		"0.000000\t{false [1 2]}\n"+
+		"0.500000\t{true [3]}\n")
+	writeFile(t, dir, "top.dut.R.0.s", "time (ns)\tint64\n"+
+		"0.100000\t5\n")
+	writeFile(t, dir, "top.dut.dat", "1\n")
+
+	r, err := Load(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(r.Processes))
+	assert.Equal(t, 2, len(r.Channels))
+
+	p := r.Process("top.dut")
+	assert.NotNil(t, p)
+	s := p.Summary()
+	assert.Equal(t, 3, s.Tokens)
+	assert.InDelta(t, 40.0, s.Energy, 1e-9)
+	assert.InDelta(t, 0.75, s.CycleTime.Mean, 1e-9)
+	assert.InDelta(t, 1.0, s.CycleTime.Max, 1e-9)
+	assert.InDelta(t, 1.5, s.Throughput, 1e-9)
+	assert.InDelta(t, 20.0, s.Power, 1e-9)
+
+	L := r.Channels[0]
+	assert.Equal(t, "top.dut", L.Process)
+	assert.Equal(t, "L", L.Name)
+	assert.Equal(t, Recv, L.Dir)
+	assert.Equal(t, "{C:bool D:[int64]}", L.Type.String())
+	assert.Equal(t, []Field{{"C", "0"}, {"D.0", "1"}, {"D.1", "2"}}, L.Type.Flatten(L.Tokens[0].Value))
+	assert.Equal(t, []Field{{"C", "1"}, {"D.0", "3"}}, L.Type.Flatten(L.Tokens[1].Value))
+
+	R := r.Channels[1]
+	assert.Equal(t, "R.0", R.Name)
+	assert.Equal(t, Send, R.Dir)
+	assert.Equal(t, []Field{{"", "5"}}, R.Type.Flatten(R.Tokens[0].Value))
+}
+
+func TestLoadChannelProcesses(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "top.m", "Start\tEnd\tEnergy (fJ)\n"+
+		"0.000000\t0.500000\t10.000000\n")
+	// top.a only logged its channels and sorts before top.m
+	writeFile(t, dir, "top.a.X.s", "time (ns)\tint64\n"+
+		"0.100000\t5\n")
+	writeFile(t, dir, "top.a.Y.r", "time (ns)\tint64\n"+
+		"0.200000\t6\n")
+
+	r, err := Load(dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, p := range r.Processes {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"top.a", "top.m"}, names)
+	assert.Len(t, r.Ports("top.a"), 2)
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{5, 1, 4, 2, 3, 6, 7, 8, 9, 10})
+	assert.Equal(t, 10, s.Count)
+	assert.InDelta(t, 5.5, s.Mean, 1e-9)
+	assert.Equal(t, 1.0, s.Min)
+	assert.Equal(t, 10.0, s.Max)
+	assert.Equal(t, 5.0, s.P50)
+	assert.Equal(t, 9.0, s.P90)
+	assert.Equal(t, 10.0, s.P99)
+}
+
+func TestVectors(t *testing.T) {
+	typ, err := ParseType("{C:bool D:[int64]}")
+	assert.NoError(t, err)
+
+	c := &Channel{
+		Process: "top.dut",
+		Name:    "L",
+		Dir:     Recv,
+		Type:    typ,
+	}
+	for _, text := range []string{"{false [1 2]}", "{true [3]}"} {
+		v, err := typ.Parse(text)
+		assert.NoError(t, err)
+		c.Tokens = append(c.Tokens, Token{Value: v})
+	}
+
+	var buf strings.Builder
+	assert.NoError(t, c.Vectors().Write(&buf))
+	assert.Equal(t, "# inject top.dut.L {C:bool D:[int64]}\n# L.C L.D.0 L.D.1\n0 1 2\n1 3\n", buf.String())
+
+	v, err := ReadVectors(strings.NewReader(buf.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, c.Vectors(), v)
+}
+
+func TestLoadTrace(t *testing.T) {
+	dir := t.TempDir()
+	w, err := trace.Create(dir, trace.JSON)
+	assert.NoError(t, err)
+
+	L1 := trace.Encode(struct {
+		C bool
+		D []int64
+	}{true, []int64{3}})
+	L0 := trace.Encode(struct {
+		C bool
+		D []int64
+	}{false, []int64{1, 2}})
+	for _, e := range []trace.Event{
+		{Process: "top.dut", Dir: trace.Cycle, Start: 0, End: 0.5, Energy: 10},
+		{Process: "top.dut", Channel: "L", Dir: trace.Recv, Start: 0.5, End: 0.5, Type: "{C:bool D:[int64]}", Value: &L1},
+		{Process: "top.dut", Channel: "L", Dir: trace.Recv, Start: 0, End: 0, Type: "{C:bool D:[int64]}", Value: &L0},
+	} {
+		assert.NoError(t, w.Write(e))
+	}
+	assert.NoError(t, w.Close())
+
+	r, err := Load(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(r.Processes))
+	assert.Equal(t, []Cycle{{0, 0.5, 10}}, r.Process("top.dut").Cycles)
+	assert.Equal(t, 1, len(r.Channels))
+
+	L := r.Channels[0]
+	assert.Equal(t, "L", L.Name)
+	assert.Equal(t, Recv, L.Dir)
+	assert.Equal(t, []Field{{"C", "0"}, {"D.0", "1"}, {"D.1", "2"}}, L.Type.Flatten(L.Tokens[0].Value))
+	assert.Equal(t, []Field{{"C", "1"}, {"D.0", "3"}}, L.Type.Flatten(L.Tokens[1].Value))
+}
+
+func TestLoadStaleTrace(t *testing.T) {
+	dir := t.TempDir()
+	w, err := trace.Create(dir, trace.JSON)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Write(trace.Event{Process: "top.old", Dir: trace.Cycle, Start: 0, End: 1, Energy: 1}))
+	assert.NoError(t, w.Close())
+
+	// the logs of a later run without a trace
+	writeFile(t, dir, "top.dut", "Start\tEnd\tEnergy (fJ)\n"+
+		"0.000000\t0.500000\t10.000000\n")
+	past := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, trace.JSONFile), past, past))
+
+	r, err := Load(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(r.Processes))
+	assert.NotNil(t, r.Process("top.dut"))
+
+	// a trace cut short by a crash is an error rather than partial data
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, trace.JSONFile), []byte(`{"process":"top.dut","dir":"cycle","start":0,"en`), 0644))
+	_, err = Load(dir)
+	assert.Error(t, err)
+}
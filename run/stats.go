@@ -0,0 +1,197 @@
+package run
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Stats summarizes a set of samples, percentiles use the nearest rank
+type Stats struct {
+	Count int
+	Mean  float64
+	Min   float64
+	Max   float64
+	P50   float64
+	P90   float64
+	P99   float64
+}
+
+func Summarize(samples []float64) Stats {
+	if len(samples) == 0 {
+		return Stats{}
+	}
+
+	sorted := make([]float64, len(samples))
+	copy(sorted, samples)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, s := range sorted {
+		sum += s
+	}
+
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return sorted[i]
+	}
+
+	return Stats{
+		Count: len(sorted),
+		Mean:  sum / float64(len(sorted)),
+		Min:   sorted[0],
+		Max:   sorted[len(sorted)-1],
+		P50:   rank(0.5),
+		P90:   rank(0.9),
+		P99:   rank(0.99),
+	}
+}
+
+// Summary is the aggregate performance of a process or of a level of the
+// process hierarchy. Times are in ns, energy in fJ, and power in uW (fJ/ns).
+type Summary struct {
+	Name      string
+	Processes int
+	Tokens    int
+	Start     float64
+	End       float64
+
+	// time between the end of consecutive cycles
+	CycleTime Stats
+	// time from the start to the end of each cycle
+	Latency Stats
+
+	// tokens/ns
+	Throughput     float64
+	Energy         float64
+	EnergyPerToken float64
+	Power          float64
+}
+
+func (s Summary) Duration() float64 {
+	return s.End - s.Start
+}
+
+func (p *Process) Summary() Summary {
+	s := Summary{
+		Name:      p.Name,
+		Processes: 1,
+		Tokens:    len(p.Cycles),
+	}
+	if len(p.Cycles) == 0 {
+		return s
+	}
+
+	s.Start = p.Cycles[0].Start
+	s.End = p.Cycles[0].End
+	latency := make([]float64, 0, len(p.Cycles))
+	period := make([]float64, 0, len(p.Cycles))
+	for i, c := range p.Cycles {
+		s.Start = math.Min(s.Start, c.Start)
+		s.End = math.Max(s.End, c.End)
+		s.Energy += c.Energy
+		latency = append(latency, c.End-c.Start)
+		if i > 0 {
+			period = append(period, c.End-p.Cycles[i-1].End)
+		}
+	}
+	s.CycleTime = Summarize(period)
+	s.Latency = Summarize(latency)
+
+	if s.Duration() > 0 {
+		s.Throughput = float64(s.Tokens) / s.Duration()
+		s.Power = s.Energy / s.Duration()
+	}
+	s.EnergyPerToken = s.Energy / float64(s.Tokens)
+	return s
+}
+
+// Levels summarizes every level of the process hierarchy that contains more
+// than one process. The throughput of a level is limited by its slowest
+// process, so tokens, cycle time and latency are taken from that process
+// while energy and power cover the whole level.
+func (r *Run) Levels() []Summary {
+	levels := map[string][]*Process{}
+	for _, p := range r.Processes {
+		parts := strings.Split(p.Name, ".")
+		for i := 1; i <= len(parts); i++ {
+			name := strings.Join(parts[0:i], ".")
+			levels[name] = append(levels[name], p)
+		}
+	}
+
+	var result []Summary
+	for name, procs := range levels {
+		if len(procs) < 2 {
+			continue
+		}
+
+		s := Summary{
+			Name: name,
+		}
+		var slowest *Summary
+		for _, p := range procs {
+			ps := p.Summary()
+			if ps.Tokens == 0 {
+				continue
+			}
+
+			if s.Processes == 0 || ps.Start < s.Start {
+				s.Start = ps.Start
+			}
+			if s.Processes == 0 || ps.End > s.End {
+				s.End = ps.End
+			}
+			s.Processes++
+			s.Energy += ps.Energy
+
+			if slowest == nil || ps.Throughput < slowest.Throughput {
+				slowest = &ps
+			}
+		}
+
+		if slowest != nil {
+			s.Tokens = slowest.Tokens
+			s.CycleTime = slowest.CycleTime
+			s.Latency = slowest.Latency
+			s.Throughput = slowest.Throughput
+			s.EnergyPerToken = s.Energy / float64(s.Tokens)
+		}
+		if s.Duration() > 0 {
+			s.Power = s.Energy / s.Duration()
+		}
+		result = append(result, s)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Summary measures the tokens that crossed this end of the channel, cycle
+// time here is the time between consecutive tokens.
+func (c *Channel) Summary() Summary {
+	s := Summary{
+		Name:   c.FullName(),
+		Tokens: len(c.Tokens),
+	}
+	if len(c.Tokens) == 0 {
+		return s
+	}
+
+	s.Start = c.Tokens[0].Time
+	s.End = c.Tokens[len(c.Tokens)-1].Time
+	period := make([]float64, 0, len(c.Tokens))
+	for i := 1; i < len(c.Tokens); i++ {
+		period = append(period, c.Tokens[i].Time-c.Tokens[i-1].Time)
+	}
+	s.CycleTime = Summarize(period)
+	if s.Duration() > 0 {
+		s.Throughput = float64(s.Tokens) / s.Duration()
+	}
+	return s
+}
@@ -0,0 +1,119 @@
+package run
+
+import (
+	"fmt"
+	"sort"
+
+	"git.broccolimicro.io/Broccoli/pr.git/chp/trace"
+)
+
+// fromTrace converts a value from the structured trace, falling back on its
+// %v representation wherever the type description has a scalar
+func fromTrace(t Type, v trace.Value) Value {
+	switch {
+	case t.Kind == List && v.Kind == trace.List:
+		result := Value{Kind: List}
+		for _, e := range v.Elems {
+			result.Elems = append(result.Elems, fromTrace(*t.Elem, e))
+		}
+		return result
+	case t.Kind == Struct && v.Kind == trace.Struct && len(t.Fields) == len(v.Fields):
+		result := Value{Kind: Struct}
+		for i, f := range v.Fields {
+			result.Elems = append(result.Elems, fromTrace(t.Fields[i].Type, f.Value))
+		}
+		return result
+	}
+	return Value{Kind: Scalar, Text: v.String()}
+}
+
+// loadTrace builds a run from the structured trace instead of the tab
+// separated logs
+func loadTrace(dir, path string) (*Run, error) {
+	events, err := trace.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Run{
+		Dir: dir,
+	}
+
+	processes := map[string]*Process{}
+	process := func(name string) *Process {
+		p, ok := processes[name]
+		if !ok {
+			p = &Process{Name: name}
+			processes[name] = p
+			r.Processes = append(r.Processes, p)
+		}
+		return p
+	}
+
+	type key struct {
+		process string
+		channel string
+		dir     Direction
+	}
+	channels := map[key]*Channel{}
+	for i, e := range events {
+		if e.Dir == trace.Cycle {
+			p := process(e.Process)
+			p.Cycles = append(p.Cycles, Cycle{
+				Start:  e.Start,
+				End:    e.End,
+				Energy: e.Energy,
+			})
+			continue
+		}
+
+		dir := Send
+		if e.Dir == trace.Recv {
+			dir = Recv
+		} else if e.Dir != trace.Send {
+			return nil, fmt.Errorf("%s: event %d: unrecognized direction '%s'", path, i, e.Dir)
+		}
+
+		k := key{e.Process, e.Channel, dir}
+		c, ok := channels[k]
+		if !ok {
+			typ, err := ParseType(e.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s: event %d: %w", path, i, err)
+			}
+			c = &Channel{
+				Process: e.Process,
+				Name:    e.Channel,
+				Dir:     dir,
+				Type:    typ,
+			}
+			channels[k] = c
+			r.Channels = append(r.Channels, c)
+			process(e.Process)
+		}
+
+		var v Value
+		if e.Value != nil {
+			v = fromTrace(c.Type, *e.Value)
+		}
+		c.Tokens = append(c.Tokens, Token{
+			Time:  e.End,
+			Value: v,
+		})
+	}
+
+	// events from different processes are interleaved
+	for _, c := range r.Channels {
+		sort.SliceStable(c.Tokens, func(i, j int) bool {
+			return c.Tokens[i].Time < c.Tokens[j].Time
+		})
+	}
+	for _, p := range r.Processes {
+		sort.SliceStable(p.Cycles, func(i, j int) bool {
+			return p.Cycles[i].End < p.Cycles[j].End
+		})
+	}
+	r.sortProcesses()
+	r.sortChannels()
+	return r, nil
+}
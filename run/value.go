@@ -0,0 +1,227 @@
+package run
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Kind int
+
+const (
+	Scalar Kind = iota
+	List
+	Struct
+)
+
+// Type mirrors the type description that chp writes into the header of each
+// channel log, for example "{C:bool D:[int64]}"
+type Type struct {
+	Kind   Kind
+	Name   string
+	Elem   *Type
+	Fields []Member
+}
+
+type Member struct {
+	Name string
+	Type Type
+}
+
+// Value is a token parsed from its %v representation. Elems holds the
+// elements of a list or the fields of a struct in declaration order.
+type Value struct {
+	Kind  Kind
+	Text  string
+	Elems []Value
+}
+
+// Field is a single scalar of a flattened value. Name is the path from the
+// top of the value, using the same naming as the top level logger:
+// list elements are numbered and struct fields are named, separated by dots.
+type Field struct {
+	Name  string
+	Value string
+}
+
+func (t Type) String() string {
+	switch t.Kind {
+	case List:
+		return "[" + t.Elem.String() + "]"
+	case Struct:
+		var b strings.Builder
+		b.WriteString("{")
+		for i, f := range t.Fields {
+			if i != 0 {
+				b.WriteString(" ")
+			}
+			b.WriteString(f.Name + ":" + f.Type.String())
+		}
+		b.WriteString("}")
+		return b.String()
+	}
+	return t.Name
+}
+
+func ParseType(desc string) (Type, error) {
+	t, n, err := parseType(desc, 0)
+	if err != nil {
+		return t, err
+	}
+	if n != len(desc) {
+		return t, fmt.Errorf("unexpected '%s' in type '%s'", desc[n:], desc)
+	}
+	return t, nil
+}
+
+func parseType(desc string, i int) (Type, int, error) {
+	if i < len(desc) && desc[i] == '[' {
+		elem, j, err := parseType(desc, i+1)
+		if err != nil {
+			return Type{}, j, err
+		}
+		if j >= len(desc) || desc[j] != ']' {
+			return Type{}, j, fmt.Errorf("expected ']' in type '%s'", desc)
+		}
+		return Type{Kind: List, Elem: &elem}, j + 1, nil
+	} else if i < len(desc) && desc[i] == '{' {
+		t := Type{Kind: Struct}
+		j := i + 1
+		for j < len(desc) && desc[j] != '}' {
+			if desc[j] == ' ' {
+				j++
+				continue
+			}
+
+			k := strings.IndexByte(desc[j:], ':')
+			if k < 0 {
+				return t, j, fmt.Errorf("expected ':' in type '%s'", desc)
+			}
+			name := desc[j : j+k]
+
+			var err error
+			var f Type
+			f, j, err = parseType(desc, j+k+1)
+			if err != nil {
+				return t, j, err
+			}
+			t.Fields = append(t.Fields, Member{name, f})
+		}
+		if j >= len(desc) {
+			return t, j, fmt.Errorf("expected '}' in type '%s'", desc)
+		}
+		return t, j + 1, nil
+	}
+
+	j := i
+	for j < len(desc) && !strings.ContainsRune(" ]}", rune(desc[j])) {
+		j++
+	}
+	return Type{Kind: Scalar, Name: desc[i:j]}, j, nil
+}
+
+// Parse interprets the %v representation of a value of this type
+func (t Type) Parse(text string) (Value, error) {
+	text = strings.TrimSpace(text)
+	if t.Kind == Scalar {
+		// only the top level scalar may contain spaces
+		return Value{Kind: Scalar, Text: text}, nil
+	}
+
+	v, n, err := t.parse(text, 0)
+	if err != nil {
+		return v, err
+	}
+	if n != len(text) {
+		return v, fmt.Errorf("unexpected '%s' in value '%s'", text[n:], text)
+	}
+	return v, nil
+}
+
+func (t Type) parse(text string, i int) (Value, int, error) {
+	if t.Kind == Scalar {
+		j := i
+		for j < len(text) && !strings.ContainsRune(" ]}", rune(text[j])) {
+			j++
+		}
+		return Value{Kind: Scalar, Text: text[i:j]}, j, nil
+	}
+
+	open, end := byte('['), byte(']')
+	if t.Kind == Struct {
+		open, end = '{', '}'
+		if strings.HasPrefix(text[i:], "&{") {
+			i++
+		}
+	}
+
+	if i >= len(text) || text[i] != open {
+		return Value{}, i, fmt.Errorf("expected '%c' in value '%s'", open, text)
+	}
+
+	v := Value{Kind: t.Kind}
+	j := i + 1
+	for j < len(text) && text[j] != end {
+		if text[j] == ' ' {
+			j++
+			continue
+		}
+
+		elem := t.Elem
+		if t.Kind == Struct {
+			if len(v.Elems) >= len(t.Fields) {
+				return v, j, fmt.Errorf("too many fields in value '%s'", text)
+			}
+			elem = &t.Fields[len(v.Elems)].Type
+		}
+
+		var err error
+		var e Value
+		e, j, err = elem.parse(text, j)
+		if err != nil {
+			return v, j, err
+		}
+		v.Elems = append(v.Elems, e)
+	}
+	if j >= len(text) {
+		return v, j, fmt.Errorf("expected '%c' in value '%s'", end, text)
+	}
+	return v, j + 1, nil
+}
+
+// Flatten splits a value into its scalars the same way the top level
+// logger.WriteElem does, writing booleans as 1 and 0.
+func (t Type) Flatten(v Value) []Field {
+	return t.flatten("", v, nil)
+}
+
+func (t Type) flatten(prefix string, v Value, result []Field) []Field {
+	join := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + "." + name
+	}
+
+	switch t.Kind {
+	case List:
+		for i, e := range v.Elems {
+			result = t.Elem.flatten(join(strconv.Itoa(i)), e, result)
+		}
+	case Struct:
+		for i, e := range v.Elems {
+			if i < len(t.Fields) {
+				result = t.Fields[i].Type.flatten(join(t.Fields[i].Name), e, result)
+			}
+		}
+	default:
+		text := v.Text
+		if text == "true" {
+			text = "1"
+		} else if text == "false" {
+			text = "0"
+		}
+		result = append(result, Field{prefix, text})
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+package run
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	Inject = "inject"
+	Expect = "expect"
+)
+
+// Vectors is the sequence of tokens on one channel of a process, flattened
+// for a digital simulator. Inject vectors are the values the environment
+// must drive into the process and expect vectors are the values it must
+// produce. The text format is:
+//
+//	# <inject|expect> <process>.<channel> <type>
+//	# <field> <field> ...
+//	<value> <value> ...
+//
+// with one line per token in the order they crossed the channel. Fields are
+// named the way the top level logger names them, a scalar channel has a
+// single field named after the channel. Booleans are written as 1 and 0.
+// Tokens containing variable length lists may have fewer values than there
+// are fields, in which case the values fill the fields from the left.
+type Vectors struct {
+	Kind    string
+	Channel string
+	Type    string
+	Fields  []string
+	Values  [][]string
+}
+
+func (c *Channel) Vectors() *Vectors {
+	v := &Vectors{
+		Kind:    Inject,
+		Channel: c.FullName(),
+		Type:    c.Type.String(),
+	}
+	if c.Dir == Send {
+		v.Kind = Expect
+	}
+
+	for _, token := range c.Tokens {
+		fields := c.Type.Flatten(token.Value)
+		values := make([]string, len(fields))
+		for i, f := range fields {
+			values[i] = f.Value
+		}
+		v.Values = append(v.Values, values)
+
+		if len(fields) > len(v.Fields) {
+			v.Fields = v.Fields[0:0]
+			for _, f := range fields {
+				if f.Name == "" {
+					v.Fields = append(v.Fields, c.Name)
+				} else {
+					v.Fields = append(v.Fields, c.Name+"."+f.Name)
+				}
+			}
+		}
+	}
+	if len(v.Fields) == 0 {
+		v.Fields = []string{c.Name}
+	}
+	return v
+}
+
+func (v *Vectors) Write(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# %s %s %s\n# %s\n", v.Kind, v.Channel, v.Type, strings.Join(v.Fields, " "))
+	if err != nil {
+		return err
+	}
+	for _, values := range v.Values {
+		_, err = fmt.Fprintln(w, strings.Join(values, " "))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ReadVectors(r io.Reader) (*Vectors, error) {
+	v := &Vectors{}
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if number == 1 {
+			header := strings.Fields(strings.TrimPrefix(line, "#"))
+			if !strings.HasPrefix(line, "#") || len(header) < 2 || (header[0] != Inject && header[0] != Expect) {
+				return nil, fmt.Errorf("%d: expected '# <inject|expect> <channel> <type>'", number)
+			}
+			v.Kind = header[0]
+			v.Channel = header[1]
+			v.Type = strings.Join(header[2:], " ")
+		} else if number == 2 {
+			if !strings.HasPrefix(line, "#") {
+				return nil, fmt.Errorf("%d: expected '# <field> <field> ...'", number)
+			}
+			v.Fields = strings.Fields(strings.TrimPrefix(line, "#"))
+		} else if line != "" {
+			values := strings.Fields(line)
+			if len(values) > len(v.Fields) {
+				return nil, fmt.Errorf("%d: found %d values for %d fields", number, len(values), len(v.Fields))
+			}
+			v.Values = append(v.Values, values)
+		}
+	}
+	return v, scanner.Err()
+}
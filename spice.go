@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"git.broccolimicro.io/Broccoli/pr.git/run"
+	"git.broccolimicro.io/Broccoli/pr.git/spice"
+)
+
+func readVectors(dir string) ([]*run.Vectors, []*run.Vectors, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(paths)
+
+	var inject, expect []*run.Vectors
+	for _, path := range paths {
+		if !strings.HasSuffix(path, "."+run.Inject) && !strings.HasSuffix(path, "."+run.Expect) {
+			continue
+		}
+
+		fptr, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		v, err := run.ReadVectors(fptr)
+		fptr.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s:%w", path, err)
+		}
+
+		if v.Kind == run.Inject {
+			inject = append(inject, v)
+		} else {
+			expect = append(expect, v)
+		}
+	}
+	return inject, expect, nil
+}
+
+var spiceCommand = &command{
+	name:  "spice",
+	usage: "[flags] <process>\n       pr spice shorten [-map <file>] [-o <file>] <netlist>\n       pr spice lengthen -map <file> [-o <file>] <file>",
+	desc:  "generate a spice simulation from that digital simulation for a particular process",
+	setup: func(fs *flag.FlagSet, c *config) func(args []string) error {
+		dir := fs.String("run", c.Run, "run directory written by the simulation")
+		tech := fs.String("tech", c.SpiceTech, "technology description")
+		vectors := fs.String("vectors", "", "inject and expect files written by 'pr test' (default <run>/vectors/<process>)")
+		out := fs.String("o", "", "output spice deck (default <process>.sp)")
+		return func(args []string) error {
+			if len(args) > 0 && (args[0] == "shorten" || args[0] == "lengthen") {
+				return spiceNames(args[0], args[1:])
+			} else if len(args) != 1 {
+				return usagef("spice: expected a process name")
+			} else if *tech == "" {
+				return usagef("spice: no technology specified")
+			}
+
+			if *vectors == "" {
+				*vectors = filepath.Join(*dir, "vectors", args[0])
+			}
+			if *out == "" {
+				*out = args[0] + ".sp"
+			}
+			return spiceBench(args[0], *tech, *vectors, *out)
+		}
+	},
+}
+
+func spiceBench(proc, techPath, vectors, out string) error {
+	tech, err := spice.LoadTech(techPath)
+	if err != nil {
+		return err
+	}
+	if tech.Netlist == "" {
+		return fmt.Errorf("%s: no netlist specified", techPath)
+	}
+
+	name := tech.Subckt
+	if name == "" {
+		name = proc[strings.LastIndex(proc, ".")+1:]
+	}
+	subckt, err := spice.FindSubckt(tech.Netlist, name)
+	if err != nil {
+		return err
+	}
+
+	inject, expect, err := readVectors(vectors)
+	if err != nil {
+		return err
+	}
+
+	fptr, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer fptr.Close()
+
+	bench := &spice.Testbench{
+		Process: proc,
+		Tech:    tech,
+		Subckt:  subckt,
+		Inject:  inject,
+		Expect:  expect,
+	}
+	return bench.Write(fptr)
+}
+
+// spiceNames implements the shorten and lengthen modes of the spice command
+func spiceNames(mode string, args []string) error {
+	fs := flag.NewFlagSet("spice "+mode, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	mapPath := fs.String("map", "", "mapping from shortened to original subckt names (default <netlist>.map for shorten)")
+	out := fs.String("o", "", "output file (default stdout)")
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Printf("usage: pr spice %s [flags] <file>\n\nFlags:\n", mode)
+		fs.SetOutput(os.Stdout)
+		fs.PrintDefaults()
+		return nil
+	} else if err != nil {
+		return usagef("spice %s: %v", mode, err)
+	} else if fs.NArg() != 1 {
+		return usagef("spice %s: expected one file", mode)
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		fptr, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer fptr.Close()
+		w = fptr
+	}
+
+	if mode == "shorten" {
+		if *mapPath == "" {
+			*mapPath = fs.Arg(0) + ".map"
+		}
+		return shorten(fs.Arg(0), *mapPath, w)
+	} else if *mapPath == "" {
+		return usagef("spice lengthen: no mapping specified")
+	}
+	return lengthen(fs.Arg(0), *mapPath, w)
+}
+
+func shorten(path, mapPath string, w io.Writer) error {
+	fptr, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fptr.Close()
+
+	netlist, err := spice.Parse(fptr)
+	if err != nil {
+		return fmt.Errorf("%s:%w", path, err)
+	}
+	mapping := netlist.Shorten()
+
+	mptr, err := os.Create(mapPath)
+	if err != nil {
+		return err
+	}
+	defer mptr.Close()
+	err = mapping.Write(mptr)
+	if err != nil {
+		return err
+	}
+
+	return netlist.Write(w)
+}
+
+func lengthen(path, mapPath string, w io.Writer) error {
+	mptr, err := os.Open(mapPath)
+	if err != nil {
+		return err
+	}
+	defer mptr.Close()
+	mapping, err := spice.ReadMapping(mptr)
+	if err != nil {
+		return fmt.Errorf("%s:%w", mapPath, err)
+	}
+
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, mapping.Lengthen(string(text)))
+	return err
+}
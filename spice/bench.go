@@ -0,0 +1,221 @@
+package spice
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"git.broccolimicro.io/Broccoli/pr.git/run"
+)
+
+// Testbench drives a cell extracted from a process with the inject vectors
+// recorded in the architectural simulation and measures its response.
+//
+// Every field of a channel is driven as digits of 1-of-radix codes with a
+// four phase protocol. A field with a single digit uses the rails
+// <field>.d[i] and a field with more digits uses <field>[j].d[i], least
+// significant digit first. Each channel is acknowledged through <channel>.e.
+// Each token is given one period: input rails rise at the start of the
+// period and reset half way through it, output enables fall half way through
+// the period and rise again at three quarters.
+//
+// The inputs are driven open loop, they don't wait for the acknowledgement
+// of the cell. A cell that is too slow for the period has its inputs reset
+// or replaced before it acknowledges them, which ack_<channel>_<k> and
+// rst_<channel>_<k> flag: the enable of the input channel is measured when
+// token k resets, where it should be low, and when the next token starts,
+// where it should be high again.
+type Testbench struct {
+	Process string
+	Tech    *Tech
+	Subckt  Subckt
+	Inject  []*run.Vectors
+	Expect  []*run.Vectors
+}
+
+type pwl struct {
+	points []string
+	t      float64
+	v      float64
+}
+
+func (p *pwl) add(t, v float64) {
+	if len(p.points) > 0 && p.t == t && p.v == v {
+		return
+	}
+	p.points = append(p.points, fmt.Sprintf("%gn %g", t, v))
+	p.t, p.v = t, v
+}
+
+func (p *pwl) String() string {
+	return "PWL(" + strings.Join(p.points, " ") + ")"
+}
+
+func ident(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func rail(field string, digit, digits, value int) string {
+	if digits <= 1 {
+		return fmt.Sprintf("%s.d[%d]", field, value)
+	}
+	return fmt.Sprintf("%s[%d].d[%d]", field, digit, value)
+}
+
+// encode splits each decimal value of a field into its digits
+func encode(values []string, radix int) ([][]int, int, error) {
+	result := make([][]int, len(values))
+	digits := 1
+	for k, text := range values {
+		v, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, 0, err
+		} else if v < 0 {
+			return nil, 0, fmt.Errorf("negative value %d cannot be encoded", v)
+		}
+
+		for v > 0 || len(result[k]) == 0 {
+			result[k] = append(result[k], int(v%int64(radix)))
+			v /= int64(radix)
+		}
+		if len(result[k]) > digits {
+			digits = len(result[k])
+		}
+	}
+	for k := range result {
+		for len(result[k]) < digits {
+			result[k] = append(result[k], 0)
+		}
+	}
+	return result, digits, nil
+}
+
+// column returns the values of one field across all tokens that have it
+func column(v *run.Vectors, field int) []string {
+	var result []string
+	for _, values := range v.Values {
+		if field < len(values) {
+			result = append(result, values[field])
+		}
+	}
+	return result
+}
+
+func (b *Testbench) local(channel string) string {
+	return strings.TrimPrefix(channel, b.Process+".")
+}
+
+func (b *Testbench) tokens() int {
+	n := 0
+	for _, v := range append(append([]*run.Vectors{}, b.Inject...), b.Expect...) {
+		if len(v.Values) > n {
+			n = len(v.Values)
+		}
+	}
+	return n
+}
+
+func (b *Testbench) Write(w io.Writer) error {
+	t := b.Tech
+	period := t.Period
+	stop := float64(b.tokens()+1) * period
+
+	fmt.Fprintf(w, "* testbench for %s\n", b.Process)
+	fmt.Fprintf(w, ".include \"%s\"\n\n", t.Netlist)
+	fmt.Fprintf(w, ".param vdd=%g\n", t.Vdd)
+	fmt.Fprintf(w, "V%s %s 0 DC %g\n", ident(t.Power), t.Power, t.Vdd)
+	fmt.Fprintf(w, "V%s %s 0 DC 0\n\n", ident(t.Ground), t.Ground)
+
+	fmt.Fprintf(w, "* inputs\n")
+	for _, v := range b.Inject {
+		for f, field := range v.Fields {
+			codes, digits, err := encode(column(v, f), t.Radix)
+			if err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+
+			for j := 0; j < digits; j++ {
+				for i := 0; i < t.Radix; i++ {
+					p := &pwl{}
+					p.add(0, 0)
+					for k, code := range codes {
+						if code[j] == i {
+							start := float64(k) * period
+							p.add(start, 0)
+							p.add(start+t.Slew, t.Vdd)
+							p.add(start+period/2, t.Vdd)
+							p.add(start+period/2+t.Slew, 0)
+						}
+					}
+					name := rail(field, j, digits, i)
+					fmt.Fprintf(w, "V%s %s 0 %s\n", ident(name), name, p)
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\n* output enables\n")
+	for _, v := range b.Expect {
+		p := &pwl{}
+		p.add(0, t.Vdd)
+		for k := range v.Values {
+			start := float64(k) * period
+			p.add(start+period/2, t.Vdd)
+			p.add(start+period/2+t.Slew, 0)
+			p.add(start+3*period/4, 0)
+			p.add(start+3*period/4+t.Slew, t.Vdd)
+		}
+		name := b.local(v.Channel) + ".e"
+		fmt.Fprintf(w, "V%s %s 0 %s\n", ident(name), name, p)
+	}
+
+	fmt.Fprintf(w, "\nxdut %s %s\n\n", strings.Join(b.Subckt.Ports, " "), b.Subckt.Name)
+
+	fmt.Fprintf(w, "* input acknowledgements\n")
+	for _, v := range b.Inject {
+		name := b.local(v.Channel) + ".e"
+		for k := range v.Values {
+			start := float64(k) * period
+			fmt.Fprintf(w, ".measure tran t_%s_%d WHEN v(%s)='vdd/2' FALL=%d\n", ident(name), k, name, k+1)
+			fmt.Fprintf(w, ".measure tran ack_%s_%d FIND v(%s) AT=%gn\n", ident(name), k, name, start+period/2)
+			fmt.Fprintf(w, ".measure tran rst_%s_%d FIND v(%s) AT=%gn\n", ident(name), k, name, start+period)
+		}
+	}
+
+	fmt.Fprintf(w, "\n* outputs\n")
+	for _, v := range b.Expect {
+		for f, field := range v.Fields {
+			codes, digits, err := encode(column(v, f), t.Radix)
+			if err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+
+			rises := map[string]int{}
+			for k, code := range codes {
+				for j, value := range code {
+					name := rail(field, j, digits, value)
+					rises[name]++
+					measure := fmt.Sprintf("t_%s_%d", ident(field), k)
+					if digits > 1 {
+						measure = fmt.Sprintf("t_%s_%d_%d", ident(field), j, k)
+					}
+					fmt.Fprintf(w, ".measure tran %s WHEN v(%s)='vdd/2' RISE=%d\n", measure, name, rises[name])
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\n* supply\n")
+	fmt.Fprintf(w, ".measure tran i_%s AVG i(V%s) FROM=0 TO=%gn\n", ident(t.Power), ident(t.Power), stop)
+	fmt.Fprintf(w, ".measure tran q_%s INTEG i(V%s) FROM=0 TO=%gn\n\n", ident(t.Power), ident(t.Power), stop)
+
+	fmt.Fprintf(w, ".tran %gn %gn\n", t.Step, stop)
+	_, err := fmt.Fprintf(w, ".end\n")
+	return err
+}
@@ -0,0 +1,170 @@
+package spice
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Measurement characterizes one cell from a spice or gate-level run, its
+// forward latency and cycle time in ns and its energy per cycle in fJ. A
+// value is NaN if the table doesn't have its column.
+type Measurement struct {
+	Cell    string
+	Latency float64
+	Cycle   float64
+	Energy  float64
+}
+
+var valuePattern = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?`)
+
+var scales = map[byte]float64{
+	't': 1e12,
+	'g': 1e9,
+	'k': 1e3,
+	'm': 1e-3,
+	'u': 1e-6,
+	'n': 1e-9,
+	'p': 1e-12,
+	'f': 1e-15,
+	'a': 1e-18,
+}
+
+// ParseValue reads a number the way spice does, with an optional scale
+// suffix like 120p or 2.5meg. Any letters after the suffix, like the s in
+// 120ps, are ignored.
+func ParseValue(s string) (float64, error) {
+	m := valuePattern.FindString(s)
+	if m == "" {
+		return 0, fmt.Errorf("invalid value '%s'", s)
+	}
+	value, err := strconv.ParseFloat(m, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	suffix := strings.ToLower(s[len(m):])
+	if strings.HasPrefix(suffix, "meg") {
+		value *= 1e6
+	} else if len(suffix) > 0 {
+		scale, ok := scales[suffix[0]]
+		if !ok && strings.Trim(suffix, "abcdefghijklmnopqrstuvwxyz") != "" {
+			return 0, fmt.Errorf("invalid value '%s'", s)
+		} else if ok {
+			value *= scale
+		}
+	}
+	return value, nil
+}
+
+// ReadMeasurements reads a table of measurements with a header naming its
+// columns: cell, latency, cycle and energy, other columns are ignored.
+// Values are in seconds and joules as spice reports them. The table is
+// either comma separated or an .mt0 file from .measure statements, where
+// rows may wrap across lines and the header ends with alter#. A table
+// without a cell column measures a single cell named by its .TITLE, or by
+// name if it has none.
+func ReadMeasurements(r io.Reader, name string) ([]Measurement, error) {
+	var header []string
+	var fields []string
+	csv := false
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '$' || line[0] == '#' || line[0] == '*' {
+			continue
+		} else if strings.HasPrefix(strings.ToUpper(line), ".TITLE") {
+			name = strings.Trim(strings.TrimSpace(line[6:]), "'\"")
+			continue
+		}
+
+		var args []string
+		if header == nil && strings.Contains(line, ",") {
+			csv = true
+		}
+		if csv {
+			for _, arg := range strings.Split(line, ",") {
+				args = append(args, strings.TrimSpace(arg))
+			}
+		} else {
+			args = strings.Fields(line)
+		}
+
+		if header == nil || (!csv && continues(header, args)) {
+			for _, arg := range args {
+				header = append(header, strings.ToLower(arg))
+			}
+			continue
+		}
+
+		if csv && len(args) != len(header) {
+			return nil, fmt.Errorf("%d: expected %d columns, found %d", number, len(header), len(args))
+		}
+		fields = append(fields, args...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("missing header")
+	} else if len(fields)%len(header) != 0 {
+		return nil, fmt.Errorf("incomplete row, expected %d columns", len(header))
+	}
+
+	var result []Measurement
+	for row := 0; row < len(fields); row += len(header) {
+		m := Measurement{
+			Cell:    name,
+			Latency: math.NaN(),
+			Cycle:   math.NaN(),
+			Energy:  math.NaN(),
+		}
+		for i, col := range header {
+			field := fields[row+i]
+			var dst *float64
+			var scale float64
+			switch col {
+			case "cell":
+				m.Cell = field
+			case "latency":
+				dst, scale = &m.Latency, 1e9
+			case "cycle":
+				dst, scale = &m.Cycle, 1e9
+			case "energy":
+				dst, scale = &m.Energy, 1e15
+			}
+			if dst != nil {
+				value, err := ParseValue(field)
+				if err != nil {
+					return nil, fmt.Errorf("row %d, %s: %w", row/len(header)+1, col, err)
+				}
+				*dst = value * scale
+			}
+		}
+		if m.Cell == "" {
+			return nil, fmt.Errorf("row %d: no cell name", row/len(header)+1)
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// continues returns whether a line of a whitespace separated table
+// continues its header. Only .mt0 headers wrap, they end with alter# and
+// their rows are all numbers.
+func continues(header []string, args []string) bool {
+	if header[len(header)-1] == "alter#" {
+		return false
+	}
+	for _, col := range header {
+		if col == "cell" {
+			return false
+		}
+	}
+	_, err := ParseValue(args[0])
+	return err != nil
+}
@@ -0,0 +1,66 @@
+package spice
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Line is one logical line of a netlist with its continuation lines joined
+type Line struct {
+	Number int
+	Text   string
+}
+
+// ReadLines splits a netlist into logical lines, joining lines that begin
+// with '+' onto the previous line.
+func ReadLines(r io.Reader) ([]Line, error) {
+	var lines []Line
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		text := scanner.Text()
+		if strings.HasPrefix(text, "+") && len(lines) > 0 {
+			lines[len(lines)-1].Text += " " + strings.TrimSpace(text[1:])
+		} else {
+			lines = append(lines, Line{number, text})
+		}
+	}
+	return lines, scanner.Err()
+}
+
+type Subckt struct {
+	Name  string
+	Ports []string
+}
+
+// Subckts lists the subcircuit definitions in a netlist
+func Subckts(r io.Reader) ([]Subckt, error) {
+	n, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return n.Subckts(), nil
+}
+
+// FindSubckt loads the definition of the named subcircuit from a netlist
+func FindSubckt(path, name string) (Subckt, error) {
+	fptr, err := os.Open(path)
+	if err != nil {
+		return Subckt{}, err
+	}
+	defer fptr.Close()
+
+	subckts, err := Subckts(fptr)
+	if err != nil {
+		return Subckt{}, err
+	}
+	for _, s := range subckts {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return Subckt{}, fmt.Errorf("subckt '%s' not found in '%s'", name, path)
+}
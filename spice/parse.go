@@ -0,0 +1,196 @@
+package spice
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Kind int
+
+const (
+	Other Kind = iota
+	Definition
+	Ends
+	Instance
+)
+
+type Param struct {
+	Name  string
+	Value string
+}
+
+func (p Param) String() string {
+	return p.Name + "=" + p.Value
+}
+
+// Statement is one logical line of a netlist. Subcircuit definitions, their
+// ends and subcircuit instances are parsed, everything else is kept as
+// written.
+type Statement struct {
+	Line
+	Kind Kind
+	// the first token as written, for example ".SUBCKT" or "x0"
+	Keyword string
+	// the subckt name for definitions and ends or the instance name
+	Name string
+	// the ports of a definition or the nets of an instance
+	Nets []string
+	// the subckt instantiated by an instance
+	Master string
+	// "params:" if the parameters were introduced by it
+	ParamsKey string
+	Params    []Param
+}
+
+type Netlist struct {
+	Statements []*Statement
+}
+
+// tokenize splits a line into its arguments, joining parameters like
+// "w = 2" into a single "w=2"
+func tokenize(text string) []string {
+	var args []string
+	for _, arg := range strings.Fields(text) {
+		n := len(args)
+		if n > 0 && (arg == "=" || strings.HasPrefix(arg, "=") || strings.HasSuffix(args[n-1], "=")) {
+			args[n-1] += arg
+		} else {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+func isParam(arg string) bool {
+	return strings.Contains(arg, "=")
+}
+
+func parseParam(arg string) Param {
+	name, value, _ := strings.Cut(arg, "=")
+	return Param{name, value}
+}
+
+func parseStatement(line Line) (*Statement, error) {
+	s := &Statement{Line: line}
+	args := tokenize(line.Text)
+	if len(args) == 0 {
+		return s, nil
+	}
+
+	s.Keyword = args[0]
+	keyword := strings.ToLower(args[0])
+	switch {
+	case keyword == ".subckt":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("%d: expected '.subckt <name> <ports...>'", line.Number)
+		}
+		s.Kind = Definition
+		s.Name = args[1]
+		for _, arg := range args[2:] {
+			if strings.ToLower(arg) == "params:" {
+				s.ParamsKey = arg
+			} else if isParam(arg) {
+				s.Params = append(s.Params, parseParam(arg))
+			} else if len(s.Params) > 0 || s.ParamsKey != "" {
+				return nil, fmt.Errorf("%d: expected parameter, found '%s'", line.Number, arg)
+			} else {
+				s.Nets = append(s.Nets, arg)
+			}
+		}
+	case keyword == ".ends":
+		s.Kind = Ends
+		if len(args) > 1 {
+			s.Name = args[1]
+		}
+	case keyword[0] == 'x':
+		s.Kind = Instance
+		s.Name = args[0]
+		end := len(args)
+		for end > 1 && isParam(args[end-1]) {
+			end--
+		}
+		for _, arg := range args[end:] {
+			s.Params = append(s.Params, parseParam(arg))
+		}
+		if end > 1 && strings.ToLower(args[end-1]) == "params:" {
+			end--
+			s.ParamsKey = args[end]
+		}
+		if end < 2 {
+			return nil, fmt.Errorf("%d: expected '%s <nets...> <subckt>'", line.Number, args[0])
+		}
+		s.Master = args[end-1]
+		s.Nets = args[1 : end-1]
+	}
+	return s, nil
+}
+
+// Parse reads a netlist, parsing subcircuit definitions and instances
+func Parse(r io.Reader) (*Netlist, error) {
+	lines, err := ReadLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Netlist{}
+	for _, line := range lines {
+		s, err := parseStatement(line)
+		if err != nil {
+			return nil, err
+		}
+		n.Statements = append(n.Statements, s)
+	}
+	return n, nil
+}
+
+func (s *Statement) String() string {
+	var args []string
+	switch s.Kind {
+	case Definition:
+		args = append(args, s.Keyword, s.Name)
+		args = append(args, s.Nets...)
+	case Ends:
+		args = append(args, s.Keyword)
+		if s.Name != "" {
+			args = append(args, s.Name)
+		}
+	case Instance:
+		args = append(args, s.Name)
+		args = append(args, s.Nets...)
+		args = append(args, s.Master)
+	default:
+		return s.Text
+	}
+
+	if s.ParamsKey != "" {
+		args = append(args, s.ParamsKey)
+	}
+	for _, p := range s.Params {
+		args = append(args, p.String())
+	}
+	return strings.Join(args, " ")
+}
+
+func (s *Statement) Subckt() Subckt {
+	return Subckt{s.Name, s.Nets}
+}
+
+func (n *Netlist) Subckts() []Subckt {
+	var result []Subckt
+	for _, s := range n.Statements {
+		if s.Kind == Definition {
+			result = append(result, s.Subckt())
+		}
+	}
+	return result
+}
+
+func (n *Netlist) Write(w io.Writer) error {
+	for _, s := range n.Statements {
+		if _, err := fmt.Fprintln(w, s.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package spice
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rename records the shortened name given to a subcircuit
+type Rename struct {
+	Short    string
+	Original string
+}
+
+type Mapping []Rename
+
+// shortName strips everything after the last letter of a subcircuit name,
+// removing template parameters like "<3,1>". It returns false if there was
+// nothing to strip.
+func shortName(name string) (string, bool) {
+	idx := strings.LastIndexFunc(name, func(r rune) bool {
+		return r < unicode.MaxASCII && unicode.IsLetter(r)
+	})
+	if idx < 0 || idx+1 >= len(name) {
+		return name, false
+	}
+	return name[0 : idx+1], true
+}
+
+// Shorten replaces the template parameters in each subcircuit name with a
+// unique id, updating the matching ends and instances. This is useful for
+// simulators with a limit on the length of a subcircuit name. Spice names
+// are case-insensitive, so instances and ends match their definition in any
+// case and a new name never matches any name that is kept, like a
+// subcircuit from a library, in any case.
+func (n *Netlist) Shorten() Mapping {
+	defined := map[string]bool{}
+	used := map[string]bool{}
+	for _, s := range n.Statements {
+		if s.Kind != Definition {
+			continue
+		}
+		defined[strings.ToLower(s.Name)] = true
+		if _, ok := shortName(s.Name); !ok {
+			used[strings.ToLower(s.Name)] = true
+		}
+	}
+	for _, s := range n.Statements {
+		if s.Kind == Instance && !defined[strings.ToLower(s.Master)] {
+			used[strings.ToLower(s.Master)] = true
+		}
+	}
+
+	var mapping Mapping
+	ids := map[string]int{}
+	short := map[string]string{}
+	for _, s := range n.Statements {
+		if s.Kind != Definition {
+			continue
+		}
+		base, ok := shortName(s.Name)
+		if !ok {
+			continue
+		}
+		key := strings.ToLower(base)
+		name := base + "_" + strconv.Itoa(ids[key])
+		for ids[key]++; used[strings.ToLower(name)]; ids[key]++ {
+			name = base + "_" + strconv.Itoa(ids[key])
+		}
+		used[strings.ToLower(name)] = true
+		short[strings.ToLower(s.Name)] = name
+		mapping = append(mapping, Rename{name, s.Name})
+	}
+
+	for _, s := range n.Statements {
+		if s.Kind == Definition || s.Kind == Ends {
+			if name, ok := short[strings.ToLower(s.Name)]; ok {
+				s.Name = name
+			}
+		} else if s.Kind == Instance {
+			if name, ok := short[strings.ToLower(s.Master)]; ok {
+				s.Master = name
+			}
+		}
+	}
+	return mapping
+}
+
+// Write writes one "<short> <original>" line for each renamed subcircuit
+func (m Mapping) Write(w io.Writer) error {
+	for _, r := range m {
+		if _, err := fmt.Fprintf(w, "%s %s\n", r.Short, r.Original); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ReadMapping(r io.Reader) (Mapping, error) {
+	var m Mapping
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			continue
+		} else if len(args) != 2 {
+			return nil, fmt.Errorf("%d: expected '<short> <original>'", number)
+		}
+		m = append(m, Rename{args[0], args[1]})
+	}
+	return m, scanner.Err()
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Lengthen replaces the shortened subcircuit names in text, for example the
+// output of a simulator, with their original names. Simulators may change
+// the case of names, so they are matched in any case.
+func (m Mapping) Lengthen(text string) string {
+	renames := make(Mapping, len(m))
+	copy(renames, m)
+	sort.SliceStable(renames, func(i, j int) bool {
+		return len(renames[i].Short) > len(renames[j].Short)
+	})
+
+	var result strings.Builder
+	for i := 0; i < len(text); {
+		found := false
+		if i == 0 || !isIdent(text[i-1]) {
+			for _, r := range renames {
+				end := i + len(r.Short)
+				if r.Short != "" && end <= len(text) && strings.EqualFold(text[i:end], r.Short) && (end >= len(text) || !isIdent(text[end])) {
+					result.WriteString(r.Original)
+					i = end
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			result.WriteByte(text[i])
+			i++
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,209 @@
+package spice
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.broccolimicro.io/Broccoli/pr.git/run"
+)
+
+func TestSubckts(t *testing.T) {
+	subckts, err := Subckts(strings.NewReader(".subckt buf L.d[0] L.d[1]\n+ L.e vdd gnd w=2\nm0 a b c d nmos\n.ends\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Subckt{{"buf", []string{"L.d[0]", "L.d[1]", "L.e", "vdd", "gnd"}}}, subckts)
+}
+
+func TestReadTech(t *testing.T) {
+	tech, err := ReadTech(strings.NewReader("netlist cells.spi\nvdd 1.8 # volts\n\nradix 4\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "cells.spi", tech.Netlist)
+	assert.Equal(t, 1.8, tech.Vdd)
+	assert.Equal(t, 4, tech.Radix)
+	assert.Equal(t, 1.0, tech.Period)
+
+	_, err = ReadTech(strings.NewReader("radix 1\n"))
+	assert.Error(t, err)
+}
+
+func TestLoadTech(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tech")
+	assert.NoError(t, os.WriteFile(path, []byte("netlist cells/cells.spi\n"), 0644))
+	tech, err := LoadTech(path)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "cells", "cells.spi"), tech.Netlist)
+
+	assert.NoError(t, os.WriteFile(path, []byte("netlist /pdk/cells.spi\n"), 0644))
+	tech, err = LoadTech(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "/pdk/cells.spi", tech.Netlist)
+}
+
+func TestTestbench(t *testing.T) {
+	tech := NewTech()
+	tech.Netlist = "cells.spi"
+	bench := &Testbench{
+		Process: "top.dut",
+		Tech:    tech,
+		Subckt:  Subckt{"dut", []string{"L.d[0]", "L.d[1]", "L.e", "R.d[0]", "R.d[1]", "R.e", "vdd", "gnd"}},
+		Inject: []*run.Vectors{{
+			Kind:    run.Inject,
+			Channel: "top.dut.L",
+			Fields:  []string{"L"},
+			Values:  [][]string{{"0"}, {"1"}},
+		}},
+		Expect: []*run.Vectors{{
+			Kind:    run.Expect,
+			Channel: "top.dut.R",
+			Fields:  []string{"R"},
+			Values:  [][]string{{"1"}, {"1"}},
+		}},
+	}
+
+	var buf strings.Builder
+	assert.NoError(t, bench.Write(&buf))
+	deck := buf.String()
+	assert.Contains(t, deck, ".include \"cells.spi\"\n")
+	assert.Contains(t, deck, "VL_d_0_ L.d[0] 0 PWL(0n 0 0.01n 1 0.5n 1 0.51n 0)\n")
+	assert.Contains(t, deck, "VL_d_1_ L.d[1] 0 PWL(0n 0 1n 0 1.01n 1 1.5n 1 1.51n 0)\n")
+	assert.Contains(t, deck, "xdut L.d[0] L.d[1] L.e R.d[0] R.d[1] R.e vdd gnd dut\n")
+	assert.Contains(t, deck, ".measure tran t_L_e_1 WHEN v(L.e)='vdd/2' FALL=2\n")
+	assert.Contains(t, deck, ".measure tran ack_L_e_1 FIND v(L.e) AT=1.5n\n")
+	assert.Contains(t, deck, ".measure tran rst_L_e_1 FIND v(L.e) AT=2n\n")
+	assert.Contains(t, deck, ".measure tran t_R_1 WHEN v(R.d[1])='vdd/2' RISE=2\n")
+	assert.Contains(t, deck, ".tran 0.001n 3n\n")
+
+	// values are decimal even with a leading zero
+	codes, digits, err := encode([]string{"010", "7"}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, digits)
+	assert.Equal(t, [][]int{{0, 1}, {7, 0}}, codes)
+}
+
+func TestParse(t *testing.T) {
+	n, err := Parse(strings.NewReader("* comment\n.SUBCKT inv<1> a b\n+ params: w=2 l = 1\n.ends inv<1>\nx0 a b inv<1> w=3\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(n.Statements))
+
+	def := n.Statements[1]
+	assert.Equal(t, Definition, def.Kind)
+	assert.Equal(t, "inv<1>", def.Name)
+	assert.Equal(t, []string{"a", "b"}, def.Nets)
+	assert.Equal(t, []Param{{"w", "2"}, {"l", "1"}}, def.Params)
+	assert.Equal(t, ".SUBCKT inv<1> a b params: w=2 l=1", def.String())
+
+	inst := n.Statements[3]
+	assert.Equal(t, Instance, inst.Kind)
+	assert.Equal(t, "x0", inst.Name)
+	assert.Equal(t, []string{"a", "b"}, inst.Nets)
+	assert.Equal(t, "inv<1>", inst.Master)
+	assert.Equal(t, []Param{{"w", "3"}}, inst.Params)
+
+	_, err = Parse(strings.NewReader("x0 w=1\n"))
+	assert.Error(t, err)
+}
+
+func TestShorten(t *testing.T) {
+	n, err := Parse(strings.NewReader(".subckt buf<1,2> a b\n.ends buf<1,2>\n.subckt buf_0 a\n.ends\n.subckt buf<3> a b\n.ends\n.subckt top a\nxa a b buf<3>\nxb a buf_0\n.ends\n"))
+	assert.NoError(t, err)
+
+	mapping := n.Shorten()
+	assert.Equal(t, Mapping{{"buf_0", "buf<1,2>"}, {"buf_1", "buf_0"}, {"buf_2", "buf<3>"}}, mapping)
+
+	var buf strings.Builder
+	assert.NoError(t, n.Write(&buf))
+	assert.Equal(t, ".subckt buf_0 a b\n.ends buf_0\n.subckt buf_1 a\n.ends\n.subckt buf_2 a b\n.ends\n.subckt top a\nxa a b buf_2\nxb a buf_1\n.ends\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, mapping.Write(&buf))
+	read, err := ReadMapping(strings.NewReader(buf.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, mapping, read)
+
+	assert.Equal(t, "xa.buf<1,2> buf<3> buf_10 buf_0", mapping.Lengthen("xa.buf_0 buf_2 buf_10 buf_1"))
+
+	// buf_0 and BUF_2 come from a library, the names differ only in case
+	n, err = Parse(strings.NewReader(".subckt Buf<1> a\n.ends\n.subckt buf<2> a\n.ends\n.subckt top a\nxa a buf_0\nxb a BUF_2\nxc a Buf<1>\n.ends\n"))
+	assert.NoError(t, err)
+	mapping = n.Shorten()
+	assert.Equal(t, Mapping{{"Buf_1", "Buf<1>"}, {"buf_3", "buf<2>"}}, mapping)
+	assert.Equal(t, "XC.Buf<1> buf<2> buf_0", mapping.Lengthen("XC.BUF_1 BUF_3 buf_0"))
+
+	// instances and ends refer to the definition in another case
+	n, err = Parse(strings.NewReader(".subckt buf<1> a\n.ends BUF<1>\n.subckt top a\nxbuf a BUF<1>\nxlib a BUF_1\n.ends\n"))
+	assert.NoError(t, err)
+	mapping = n.Shorten()
+	assert.Equal(t, Mapping{{"buf_0", "buf<1>"}}, mapping)
+
+	buf.Reset()
+	assert.NoError(t, n.Write(&buf))
+	assert.Equal(t, ".subckt buf_0 a\n.ends buf_0\n.subckt top a\nxbuf a buf_0\nxlib a BUF_1\n.ends\n", buf.String())
+}
+
+func TestParseValue(t *testing.T) {
+	for text, value := range map[string]float64{
+		"1.5":    1.5,
+		"120p":   120e-12,
+		"120ps":  120e-12,
+		"2.5meg": 2.5e6,
+		"-3e-15": -3e-15,
+		"4fJ":    4e-15,
+	} {
+		v, err := ParseValue(text)
+		assert.NoError(t, err, text)
+		assert.InDelta(t, value, v, math.Abs(value)*1e-9, text)
+	}
+
+	_, err := ParseValue("failed")
+	assert.Error(t, err)
+	_, err = ParseValue("1.5%")
+	assert.Error(t, err)
+}
+
+func TestReadMeasurements(t *testing.T) {
+	m, err := ReadMeasurements(strings.NewReader(`# cell, forward latency (s), cycle time (s), energy (J)
+cell,latency,cycle,energy
+Buffer[...],100p,330p,10f
+Counter,0.2n,0.5n,1.2e-14
+`), "cells")
+	assert.NoError(t, err)
+	assert.Len(t, m, 2)
+	assert.Equal(t, "Buffer[...]", m[0].Cell)
+	assert.InDelta(t, 0.1, m[0].Latency, 1e-9)
+	assert.InDelta(t, 0.33, m[0].Cycle, 1e-9)
+	assert.InDelta(t, 10.0, m[0].Energy, 1e-9)
+	assert.Equal(t, "Counter", m[1].Cell)
+	assert.InDelta(t, 12.0, m[1].Energy, 1e-9)
+
+	// rows and the header wrap in an .mt0 file
+	m, err = ReadMeasurements(strings.NewReader(`$DATA1 SOURCE='HSPICE' VERSION='2019.06'
+.TITLE 'Buffer[...]'
+ latency          cycle            energy           temper
+ alter#
+ 1.000e-10        3.300e-10        1.000e-14        25.0000
+ 1
+ 1.200e-10        3.500e-10
+ 1.100e-14        25.0000          2
+`), "buffer")
+	assert.NoError(t, err)
+	assert.Len(t, m, 2)
+	assert.Equal(t, "Buffer[...]", m[1].Cell)
+	assert.InDelta(t, 0.12, m[1].Latency, 1e-9)
+	assert.InDelta(t, 0.35, m[1].Cycle, 1e-9)
+	assert.InDelta(t, 11.0, m[1].Energy, 1e-9)
+
+	m, err = ReadMeasurements(strings.NewReader("latency\n1n\n"), "buffer")
+	assert.NoError(t, err)
+	assert.Equal(t, "buffer", m[0].Cell)
+	assert.True(t, math.IsNaN(m[0].Cycle))
+
+	_, err = ReadMeasurements(strings.NewReader("cell,latency\nBuffer,failed\n"), "cells")
+	assert.ErrorContains(t, err, "row 1, latency: invalid value 'failed'")
+	_, err = ReadMeasurements(strings.NewReader("cell,latency\nBuffer\n"), "cells")
+	assert.Error(t, err)
+}
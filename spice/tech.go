@@ -0,0 +1,119 @@
+package spice
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Tech describes the process and the handshake protocol used to drive a
+// cell in simulation. It is read from a text file with one "<key> <value>"
+// pair per line and '#' comments:
+//
+//	netlist cells.spi   # extracted netlist, relative to the tech file
+//	subckt  buffer      # defaults to the last component of the process name
+//	vdd     1.8         # supply voltage (V)
+//	power   vdd         # supply node
+//	ground  gnd         # ground node
+//	slew    0.02        # input rise and fall time (ns)
+//	period  2.0         # time allotted to each token (ns)
+//	step    0.001       # transient time step (ns)
+//	radix   2           # each value is driven as digits of 1-of-radix codes
+type Tech struct {
+	Netlist string
+	Subckt  string
+	Vdd     float64
+	Power   string
+	Ground  string
+	Slew    float64
+	Period  float64
+	Step    float64
+	Radix   int
+}
+
+func NewTech() *Tech {
+	return &Tech{
+		Vdd:    1.0,
+		Power:  "vdd",
+		Ground: "gnd",
+		Slew:   0.01,
+		Period: 1.0,
+		Step:   0.001,
+		Radix:  2,
+	}
+}
+
+func ReadTech(r io.Reader) (*Tech, error) {
+	t := NewTech()
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[0:i]
+		}
+
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		} else if len(args) != 2 {
+			return nil, fmt.Errorf("%d: expected '<key> <value>'", number)
+		}
+
+		var err error
+		switch args[0] {
+		case "netlist":
+			t.Netlist = args[1]
+		case "subckt":
+			t.Subckt = args[1]
+		case "power":
+			t.Power = args[1]
+		case "ground":
+			t.Ground = args[1]
+		case "vdd":
+			t.Vdd, err = strconv.ParseFloat(args[1], 64)
+		case "slew":
+			t.Slew, err = strconv.ParseFloat(args[1], 64)
+		case "period":
+			t.Period, err = strconv.ParseFloat(args[1], 64)
+		case "step":
+			t.Step, err = strconv.ParseFloat(args[1], 64)
+		case "radix":
+			t.Radix, err = strconv.Atoi(args[1])
+			if err == nil && t.Radix < 2 {
+				err = fmt.Errorf("radix must be at least 2")
+			}
+		default:
+			err = fmt.Errorf("unrecognized key '%s'", args[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", number, err)
+		}
+	}
+	return t, scanner.Err()
+}
+
+func LoadTech(path string) (*Tech, error) {
+	fptr, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fptr.Close()
+
+	t, err := ReadTech(fptr)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+
+	// the testbench may be written and simulated anywhere
+	if t.Netlist != "" && !filepath.IsAbs(t.Netlist) {
+		t.Netlist, err = filepath.Abs(filepath.Join(filepath.Dir(path), t.Netlist))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
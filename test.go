@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"git.broccolimicro.io/Broccoli/pr.git/run"
+)
+
+func writeVectors(dir string, c *run.Channel) error {
+	v := c.Vectors()
+	fptr, err := os.Create(filepath.Join(dir, c.Name+"."+v.Kind))
+	if err != nil {
+		return err
+	}
+	defer fptr.Close()
+	return v.Write(fptr)
+}
+
+var testCommand = &command{
+	name:  "test",
+	usage: "[flags] <process>",
+	desc:  "use the architectural simulation to create inject and expect files for the digital simulator",
+	setup: func(fs *flag.FlagSet, c *config) func(args []string) error {
+		dir := fs.String("run", c.Run, "run directory written by the simulation")
+		out := fs.String("o", "", "output directory (default <run>/vectors/<process>)")
+		return func(args []string) error {
+			if len(args) != 1 {
+				return usagef("test: expected a process name")
+			}
+			if *out == "" {
+				*out = filepath.Join(*dir, "vectors", args[0])
+			}
+			return test(*dir, args[0], *out)
+		}
+	},
+}
+
+func test(dir, proc, out string) error {
+	r, err := run.Load(dir)
+	if err != nil {
+		return err
+	}
+
+	ports := r.Ports(proc)
+	if len(ports) == 0 {
+		return fmt.Errorf("no channel logs found for process '%s' in '%s'", proc, dir)
+	}
+
+	err = os.MkdirAll(out, 0755)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range ports {
+		err = writeVectors(out, c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}